@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/pigeio/todo-api/internal/database"
 	"github.com/pigeio/todo-api/internal/handlers"
+	"github.com/pigeio/todo-api/internal/jobs"
 	"github.com/pigeio/todo-api/internal/middleware" // Import middleware
 	"github.com/pigeio/todo-api/internal/repository"
 	"github.com/pigeio/todo-api/internal/utils" // NEW IMPORT
@@ -28,15 +30,68 @@ func main() {
 		log.Fatal("JWT_SECRET is not set in .env file")
 	}
 
+	// Deleted todos stay in the trash this long before they are purged
+	trashRetentionDays := 30
+	if days := os.Getenv("TRASH_RETENTION_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			log.Fatal("TRASH_RETENTION_DAYS must be a positive number of days")
+		}
+		trashRetentionDays = n
+	}
+
+	// Deletions are kept this long for offline clients to sync; older sync tokens are refused
+	tombstoneRetentionDays := 90
+	if days := os.Getenv("SYNC_TOMBSTONE_RETENTION_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			log.Fatal("SYNC_TOMBSTONE_RETENTION_DAYS must be a positive number of days")
+		}
+		tombstoneRetentionDays = n
+	}
+
+	// Responses to requests with an Idempotency-Key are replayed this long
+	idempotencyKeyTTL := 24 * time.Hour
+	if hours := os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"); hours != "" {
+		n, err := strconv.Atoi(hours)
+		if err != nil || n < 1 {
+			log.Fatal("IDEMPOTENCY_KEY_TTL_HOURS must be a positive number of hours")
+		}
+		idempotencyKeyTTL = time.Duration(n) * time.Hour
+	}
+
 	db, err := database.NewPostgresDB(os.Getenv("DATABASE_URL"))
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer db.Close()
 
+	// Background jobs and administrative operations connect as a role that may
+	// bypass row-level security (see migrations/000022); requests never do
+	systemDatabaseURL := os.Getenv("SYSTEM_DATABASE_URL")
+	if systemDatabaseURL == "" {
+		log.Fatal("SYSTEM_DATABASE_URL is not set in .env file")
+	}
+	systemDB, err := database.NewPostgresDB(systemDatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to database as the system role:", err)
+	}
+	defer systemDB.Close()
+
 	// Initialize REAL repositories
 	userRepo := repository.NewUserRepository(db)
-	todoRepo := repository.NewTodoRepository(db)
+	todoRepo := repository.NewTodoRepository(db, systemDB)
+	commentRepo := repository.NewCommentRepository(db)
+	projectRepo := repository.NewProjectRepository(db)
+	shareRepo := repository.NewShareRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	shareLinkRepo := repository.NewShareLinkRepository(db)
+	workspaceRepo := repository.NewWorkspaceRepository(db, systemDB)
+	settingsRepo := repository.NewSettingsRepository(db)
+	savedViewRepo := repository.NewSavedViewRepository(db)
+	workflowRepo := repository.NewWorkflowStateRepository(db, systemDB)
+	undoRepo := repository.NewUndoRepository(db, systemDB)
+	idempotencyRepo := repository.NewIdempotencyRepository(db, systemDB)
 
 	// Initialize REAL Token Generator
 	tokenGenerator, err := utils.NewJWTGenerator(jwtSecret)
@@ -48,24 +103,197 @@ func main() {
 	authHandler := handlers.NewAuthHandler(userRepo, tokenGenerator)
 
 	// Note: You must also update NewTodoHandler to accept its interface
-	todoHandler := handlers.NewTodoHandler(todoRepo)
+	todoHandler := handlers.NewTodoHandler(todoRepo, projectRepo, notificationRepo, workflowRepo, settingsRepo, undoRepo)
+	commentHandler := handlers.NewCommentHandler(commentRepo, todoRepo)
+	projectHandler := handlers.NewProjectHandler(projectRepo)
+	shareHandler := handlers.NewShareHandler(shareRepo, todoRepo, projectRepo, userRepo, workspaceRepo)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo)
+	shareLinkHandler := handlers.NewShareLinkHandler(shareLinkRepo, todoRepo, projectRepo)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceRepo)
+	settingsHandler := handlers.NewSettingsHandler(settingsRepo)
+	savedViewHandler := handlers.NewSavedViewHandler(savedViewRepo, todoRepo)
+	workflowHandler := handlers.NewWorkflowHandler(workflowRepo, projectRepo, todoRepo)
+	trashHandler := handlers.NewTrashHandler(todoRepo, trashRetentionDays)
+	undoHandler := handlers.NewUndoHandler(undoRepo)
+	syncHandler := handlers.NewSyncHandler(todoHandler, tombstoneRetentionDays)
 
 	r := mux.NewRouter()
 	r.HandleFunc("/register", authHandler.Register).Methods("POST")
 	r.HandleFunc("/login", authHandler.Login).Methods("POST")
 
-	api := r.PathPrefix("/todos").Subrouter()
+	// Public, unauthenticated read-only views behind share links.
+	// Rate limited like everything else to slow down guessing link passwords.
+	shared := r.PathPrefix("/shared").Subrouter()
+	shared.Use(middleware.RateLimitMiddleware)
+	shared.HandleFunc("/{token}", shareLinkHandler.GetSharedView).Methods("GET")
 
 	// --- CHANGED ---
 	// We now *call* AuthMiddleware, passing it the dependency it needs
+	authMiddleware := middleware.AuthMiddleware(tokenGenerator)
+	// Tenant-scoped routes also resolve the active workspace (X-Workspace-ID)
+	workspaceMiddleware := middleware.WorkspaceMiddleware(workspaceRepo)
+	// Authenticated POSTs and PATCHes can be retried safely with an Idempotency-Key
+	idempotencyMiddleware := middleware.IdempotencyMiddleware(idempotencyRepo, idempotencyKeyTTL)
+
+	api := r.PathPrefix("/todos").Subrouter()
 	api.Use(middleware.RateLimitMiddleware)
 	//api.Use(middleware.ThrottleMiddleware)
-	api.Use(middleware.AuthMiddleware(tokenGenerator))
+	api.Use(authMiddleware)
+	api.Use(workspaceMiddleware)
+	api.Use(idempotencyMiddleware)
 
 	api.HandleFunc("", todoHandler.GetTodos).Methods("GET")
 	api.HandleFunc("", todoHandler.CreateTodo).Methods("POST")
+	api.HandleFunc("/archive-completed", todoHandler.ArchiveCompleted).Methods("POST")
+	api.HandleFunc("/bulk", todoHandler.BulkTodos).Methods("POST")
+	api.HandleFunc("/{id}", todoHandler.GetTodo).Methods("GET")
 	api.HandleFunc("/{id}", todoHandler.UpdateTodo).Methods("PUT")
+	api.HandleFunc("/{id}", todoHandler.PatchTodo).Methods("PATCH")
 	api.HandleFunc("/{id}", todoHandler.DeleteTodo).Methods("DELETE")
+	api.HandleFunc("/{id}/similar", todoHandler.GetSimilarTodos).Methods("GET")
+	api.HandleFunc("/{id}/move", todoHandler.MoveTodo).Methods("POST")
+	api.HandleFunc("/{id}/restore", trashHandler.RestoreTodo).Methods("POST")
+	api.HandleFunc("/{id}/archive", todoHandler.ArchiveTodo).Methods("POST")
+	api.HandleFunc("/{id}/history", todoHandler.GetTodoHistory).Methods("GET")
+	api.HandleFunc("/{id}/revert", todoHandler.RevertTodo).Methods("POST")
+	api.HandleFunc("/{id}/unarchive", todoHandler.UnarchiveTodo).Methods("POST")
+	api.HandleFunc("/{id}/transitions", workflowHandler.GetTodoTransitions).Methods("GET")
+	api.HandleFunc("/{id}/dependencies", todoHandler.GetDependencies).Methods("GET")
+	api.HandleFunc("/{id}/dependencies", todoHandler.AddDependency).Methods("POST")
+	api.HandleFunc("/{id}/dependencies/{blockerId}", todoHandler.RemoveDependency).Methods("DELETE")
+
+	api.HandleFunc("/{id}/comments", commentHandler.GetComments).Methods("GET")
+	api.HandleFunc("/{id}/comments", commentHandler.CreateComment).Methods("POST")
+	api.HandleFunc("/{id}/comments/{commentId}", commentHandler.UpdateComment).Methods("PUT")
+	api.HandleFunc("/{id}/comments/{commentId}", commentHandler.DeleteComment).Methods("DELETE")
+
+	api.HandleFunc("/{id}/shares", shareHandler.GetTodoShares).Methods("GET")
+	api.HandleFunc("/{id}/shares", shareHandler.ShareTodo).Methods("POST")
+	api.HandleFunc("/{id}/shares/{shareId}", shareHandler.RevokeTodoShare).Methods("DELETE")
+
+	projects := r.PathPrefix("/projects").Subrouter()
+	projects.Use(middleware.RateLimitMiddleware)
+	projects.Use(authMiddleware)
+	projects.Use(workspaceMiddleware)
+	projects.Use(idempotencyMiddleware)
+
+	projects.HandleFunc("", projectHandler.GetProjects).Methods("GET")
+	projects.HandleFunc("", projectHandler.CreateProject).Methods("POST")
+	projects.HandleFunc("/{id}", projectHandler.GetProject).Methods("GET")
+	projects.HandleFunc("/{id}", projectHandler.UpdateProject).Methods("PUT")
+	projects.HandleFunc("/{id}", projectHandler.DeleteProject).Methods("DELETE")
+	projects.HandleFunc("/{id}/shares", shareHandler.GetProjectShares).Methods("GET")
+	projects.HandleFunc("/{id}/shares", shareHandler.ShareProject).Methods("POST")
+	projects.HandleFunc("/{id}/shares/{shareId}", shareHandler.RevokeProjectShare).Methods("DELETE")
+	projects.HandleFunc("/{id}/states", workflowHandler.GetStates).Methods("GET")
+	projects.HandleFunc("/{id}/states", workflowHandler.CreateState).Methods("POST")
+	projects.HandleFunc("/{id}/states/{stateId}", workflowHandler.UpdateState).Methods("PUT")
+	projects.HandleFunc("/{id}/states/{stateId}", workflowHandler.DeleteState).Methods("DELETE")
+	projects.HandleFunc("/{id}/board", workflowHandler.GetBoard).Methods("GET")
+	projects.HandleFunc("/{id}/board/{stateId}", workflowHandler.GetBoardColumn).Methods("GET")
+
+	views := r.PathPrefix("/views").Subrouter()
+	views.Use(middleware.RateLimitMiddleware)
+	views.Use(authMiddleware)
+	views.Use(workspaceMiddleware)
+	views.Use(idempotencyMiddleware)
+
+	views.HandleFunc("", savedViewHandler.GetViews).Methods("GET")
+	views.HandleFunc("", savedViewHandler.CreateView).Methods("POST")
+	views.HandleFunc("/{id}", savedViewHandler.GetView).Methods("GET")
+	views.HandleFunc("/{id:[0-9]+}", savedViewHandler.UpdateView).Methods("PUT")
+	views.HandleFunc("/{id:[0-9]+}", savedViewHandler.DeleteView).Methods("DELETE")
+	views.HandleFunc("/{id}/todos", savedViewHandler.GetViewTodos).Methods("GET")
+
+	trash := r.PathPrefix("/trash").Subrouter()
+	trash.Use(middleware.RateLimitMiddleware)
+	trash.Use(authMiddleware)
+	trash.Use(workspaceMiddleware)
+
+	trash.HandleFunc("", trashHandler.GetTrash).Methods("GET")
+	trash.HandleFunc("", trashHandler.EmptyTrash).Methods("DELETE")
+
+	undo := r.PathPrefix("/undo").Subrouter()
+	undo.Use(middleware.RateLimitMiddleware)
+	undo.Use(authMiddleware)
+	undo.Use(workspaceMiddleware)
+	undo.Use(idempotencyMiddleware)
+
+	undo.HandleFunc("/{token}", undoHandler.Undo).Methods("POST")
+
+	activity := r.PathPrefix("/activity").Subrouter()
+	activity.Use(middleware.RateLimitMiddleware)
+	activity.Use(authMiddleware)
+	activity.Use(workspaceMiddleware)
+
+	activity.HandleFunc("", todoHandler.GetActivity).Methods("GET")
+
+	sync := r.PathPrefix("/sync").Subrouter()
+	sync.Use(middleware.RateLimitMiddleware)
+	sync.Use(authMiddleware)
+	sync.Use(workspaceMiddleware)
+	sync.Use(idempotencyMiddleware)
+
+	sync.HandleFunc("", syncHandler.Sync).Methods("POST")
+
+	invitations := r.PathPrefix("/invitations").Subrouter()
+	invitations.Use(middleware.RateLimitMiddleware)
+	invitations.Use(authMiddleware)
+	invitations.Use(idempotencyMiddleware)
+
+	invitations.HandleFunc("", shareHandler.GetInvitations).Methods("GET")
+	invitations.HandleFunc("/{id}/accept", shareHandler.AcceptInvitation).Methods("POST")
+	invitations.HandleFunc("/{id}/decline", shareHandler.DeclineInvitation).Methods("POST")
+
+	notifications := r.PathPrefix("/notifications").Subrouter()
+	notifications.Use(middleware.RateLimitMiddleware)
+	notifications.Use(authMiddleware)
+	notifications.Use(idempotencyMiddleware)
+
+	notifications.HandleFunc("", notificationHandler.GetNotifications).Methods("GET")
+	notifications.HandleFunc("/{id}/read", notificationHandler.MarkNotificationRead).Methods("POST")
+
+	shareLinks := r.PathPrefix("/share-links").Subrouter()
+	shareLinks.Use(middleware.RateLimitMiddleware)
+	shareLinks.Use(authMiddleware)
+	shareLinks.Use(workspaceMiddleware)
+	shareLinks.Use(idempotencyMiddleware)
+
+	shareLinks.HandleFunc("", shareLinkHandler.GetShareLinks).Methods("GET")
+	shareLinks.HandleFunc("", shareLinkHandler.CreateShareLink).Methods("POST")
+	shareLinks.HandleFunc("/{id}", shareLinkHandler.RevokeShareLink).Methods("DELETE")
+
+	workspaces := r.PathPrefix("/workspaces").Subrouter()
+	workspaces.Use(middleware.RateLimitMiddleware)
+	workspaces.Use(authMiddleware)
+	workspaces.Use(idempotencyMiddleware)
+
+	workspaces.HandleFunc("", workspaceHandler.GetWorkspaces).Methods("GET")
+	workspaces.HandleFunc("", workspaceHandler.CreateWorkspace).Methods("POST")
+	workspaces.HandleFunc("/invitations", workspaceHandler.GetInvitations).Methods("GET")
+	workspaces.HandleFunc("/invitations/{id:[0-9]+}/accept", workspaceHandler.AcceptInvitation).Methods("POST")
+	workspaces.HandleFunc("/invitations/{id:[0-9]+}/decline", workspaceHandler.DeclineInvitation).Methods("POST")
+	workspaces.HandleFunc("/{id:[0-9]+}/members", workspaceHandler.GetMembers).Methods("GET")
+	workspaces.HandleFunc("/{id:[0-9]+}/members/{userId:[0-9]+}", workspaceHandler.RemoveMember).Methods("DELETE")
+	workspaces.HandleFunc("/{id:[0-9]+}/invitations", workspaceHandler.InviteMember).Methods("POST")
+	workspaces.HandleFunc("/{id:[0-9]+}/transfer", workspaceHandler.TransferOwnership).Methods("POST")
+
+	settings := r.PathPrefix("/settings").Subrouter()
+	settings.Use(middleware.RateLimitMiddleware)
+	settings.Use(authMiddleware)
+
+	settings.HandleFunc("", settingsHandler.GetSettings).Methods("GET")
+	settings.HandleFunc("", settingsHandler.UpdateSettings).Methods("PUT")
+
+	// Batches send their sub-requests back through r, where each one is rate
+	// limited and authenticated by its own route like a separate call
+	batchHandler := handlers.NewBatchHandler(r)
+
+	batch := r.PathPrefix("/batch").Subrouter()
+	batch.Use(authMiddleware)
+	batch.Use(idempotencyMiddleware)
+
+	batch.HandleFunc("", batchHandler.Batch).Methods("POST")
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -80,6 +308,53 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Background jobs, stopped on shutdown
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go jobs.Every(jobCtx, "rebalance todo positions", time.Hour, func(ctx context.Context) error {
+		n, err := todoRepo.RebalancePositions(ctx)
+		if n > 0 {
+			log.Printf("Rebalanced todo positions in %d workspaces", n)
+		}
+		return err
+	})
+
+	go jobs.Every(jobCtx, "purge trash", time.Hour, func(ctx context.Context) error {
+		cutoff := time.Now().AddDate(0, 0, -trashRetentionDays)
+		n, err := todoRepo.PurgeTrash(ctx, cutoff)
+		if n > 0 {
+			log.Printf("Purged %d todos from the trash", n)
+		}
+		return err
+	})
+
+	go jobs.Every(jobCtx, "auto-archive completed todos", time.Hour, func(ctx context.Context) error {
+		n, err := todoRepo.AutoArchive(ctx)
+		if n > 0 {
+			log.Printf("Archived %d completed todos", n)
+		}
+		return err
+	})
+
+	go jobs.Every(jobCtx, "purge undo tokens", time.Hour, func(ctx context.Context) error {
+		_, err := undoRepo.PurgeExpired(ctx)
+		return err
+	})
+
+	go jobs.Every(jobCtx, "purge idempotency keys", time.Hour, func(ctx context.Context) error {
+		_, err := idempotencyRepo.PurgeExpired(ctx)
+		return err
+	})
+
+	// Tombstones outlive the tokens that need them by a day, for deletions by
+	// transactions that were still running when a token was issued
+	go jobs.Every(jobCtx, "purge sync tombstones", time.Hour, func(ctx context.Context) error {
+		cutoff := time.Now().AddDate(0, 0, -tombstoneRetentionDays-1)
+		_, err := todoRepo.PurgeTombstones(ctx, cutoff)
+		return err
+	})
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Server starting on port %s", port)
@@ -94,6 +369,7 @@ func main() {
 	<-quit
 
 	log.Println("Server shutting down...")
+	stopJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -0,0 +1,137 @@
+// Package filter parses the filter expressions accepted by listing endpoints,
+// e.g. `completed:false AND (title:report OR assignee:me) AND created_at<2026-11-01`.
+//
+// Parsing validates every field, operator and value against a Fields whitelist,
+// so a parsed Expr only ever names known fields with values of the right type.
+// Turning it into SQL is up to the repository, which binds every value as a
+// query parameter.
+package filter
+
+import (
+	"fmt"
+	"time"
+)
+
+// Expr is a node of a parsed filter: And, Or, Not or Comparison.
+type Expr interface {
+	expr()
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+// Comparison is a single `field op value` term.
+type Comparison struct {
+	Field string
+	Op    Op
+	Value Value
+	// Pos is the byte offset of the field name in the input
+	Pos int
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+
+type Op string
+
+const (
+	OpMatch        Op = ":" // contains for text, same day for dates, equals otherwise
+	OpEqual        Op = "="
+	OpNotEqual     Op = "!="
+	OpLess         Op = "<"
+	OpLessEqual    Op = "<="
+	OpGreater      Op = ">"
+	OpGreaterEqual Op = ">="
+)
+
+// FieldType decides which operators and values a field accepts.
+type FieldType int
+
+const (
+	Bool FieldType = iota
+	Number
+	Text
+	Time
+	// User is a user ID or `me`
+	User
+)
+
+type Field struct {
+	Type FieldType
+	// Nullable fields also accept `none` (or `null`) as a value
+	Nullable bool
+}
+
+// Fields is the whitelist of fields an expression may use.
+type Fields map[string]Field
+
+type ValueKind int
+
+const (
+	KindNull ValueKind = iota
+	KindBool
+	KindNumber
+	KindText
+	KindTime
+	// KindMe is the user running the filter
+	KindMe
+)
+
+// Value is a comparison's right-hand side, already converted to the field's type.
+type Value struct {
+	Kind   ValueKind
+	Bool   bool
+	Number int
+	Text   string
+	Time   TimeValue
+}
+
+// TimeValue is either an absolute time or one relative to when the filter
+// runs, so saved filters like `created_at>-7d` stay current.
+type TimeValue struct {
+	Absolute time.Time
+	Relative bool
+	// Days from the start of today (today, tomorrow, -7d, 2w), or Duration from now (now, -12h)
+	Days     int
+	Duration time.Duration
+	// DateOnly values name a whole day, which `:` matches in full
+	DateOnly bool
+}
+
+// Resolve returns the point in time the value stands for when evaluated at now.
+func (v TimeValue) Resolve(now time.Time) time.Time {
+	if !v.Relative {
+		return v.Absolute
+	}
+	if v.DateOnly {
+		y, m, d := now.Date()
+		return time.Date(y, m, d+v.Days, 0, 0, 0, 0, now.Location())
+	}
+	return now.Add(v.Duration)
+}
+
+// Error points at the token a filter was rejected for.
+type Error struct {
+	// Pos is the byte offset of Token in the input
+	Pos     int
+	Token   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s at end of filter", e.Message)
+	}
+	return fmt.Sprintf("%s at position %d near %q", e.Message, e.Pos, e.Token)
+}
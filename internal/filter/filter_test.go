@@ -0,0 +1,130 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testFields = Fields{
+	"completed":  {Type: Bool},
+	"title":      {Type: Text},
+	"project":    {Type: Number, Nullable: true},
+	"assignee":   {Type: User, Nullable: true},
+	"created_at": {Type: Time},
+}
+
+func TestParse(t *testing.T) {
+	day := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in   string
+		want Expr
+	}{
+		{"", nil},
+		{"completed:false", Comparison{Field: "completed", Op: OpMatch, Value: Value{Kind: KindBool}}},
+		{
+			`completed:false AND (title:"weekly report" OR assignee:me) AND created_at<2026-11-01`,
+			And{
+				Left: And{
+					Left: Comparison{Field: "completed", Op: OpMatch, Value: Value{Kind: KindBool}},
+					Right: Or{
+						Left:  Comparison{Field: "title", Op: OpMatch, Value: Value{Kind: KindText, Text: "weekly report"}, Pos: 21},
+						Right: Comparison{Field: "assignee", Op: OpMatch, Value: Value{Kind: KindMe}, Pos: 46},
+					},
+				},
+				Right: Comparison{Field: "created_at", Op: OpLess, Value: Value{Kind: KindTime, Time: TimeValue{Absolute: day, DateOnly: true}}, Pos: 63},
+			},
+		},
+		{"NOT project:none", Not{Expr: Comparison{Field: "project", Op: OpMatch, Value: Value{Kind: KindNull}, Pos: 4}}},
+		{"created_at>=-7d", Comparison{Field: "created_at", Op: OpGreaterEqual, Value: Value{Kind: KindTime, Time: TimeValue{Relative: true, DateOnly: true, Days: -7}}}},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in, testFields)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) =\n%#v\nwant\n%#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	got, err := Parse("completed:true OR completed:false AND project:1", testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	or, ok := got.(Or)
+	if !ok {
+		t.Fatalf("top level = %T, want Or", got)
+	}
+	if _, ok := or.Right.(And); !ok {
+		t.Errorf("right of OR = %T, want And", or.Right)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in    string
+		pos   int
+		token string
+	}{
+		{"tag:work", 0, "tag"},
+		{"completed:maybe", 10, "maybe"},
+		{"title>abc", 6, "abc"},
+		{"completed:true AND", 18, ""},
+		{"(completed:true", 15, ""},
+		{"completed:true)", 14, ")"},
+		{"completed true", 10, "true"},
+		{`title:"open`, 6, `"open`},
+		{"created_at<soon", 11, "soon"},
+		{"project>none", 8, "none"},
+		{"assignee:bob", 9, "bob"},
+		{"project:2147483648", 8, "2147483648"},
+		{"completed:true OR OR completed:false", 18, "OR"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.in, testFields)
+		var ferr *Error
+		if !errors.As(err, &ferr) {
+			t.Errorf("Parse(%q) error = %v, want *Error", tt.in, err)
+			continue
+		}
+		if ferr.Pos != tt.pos || ferr.Token != tt.token {
+			t.Errorf("Parse(%q) error at %d %q, want %d %q (%v)", tt.in, ferr.Pos, ferr.Token, tt.pos, tt.token, ferr)
+		}
+	}
+}
+
+func TestTimeValueResolve(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"today", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"tomorrow", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"-7d", time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)},
+		{"+2w", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"-12h", now.Add(-12 * time.Hour)},
+		{"now", now},
+		{"2026-01-02T03:04:05Z", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		v, err := parseTime(tt.in)
+		if err != nil {
+			t.Errorf("parseTime(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got := v.Resolve(now); !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q).Resolve = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
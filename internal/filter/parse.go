@@ -0,0 +1,379 @@
+package filter
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits keeping a single filter cheap to parse and to run
+const (
+	MaxLength   = 1000
+	MaxTerms    = 50
+	MaxDepth    = 20
+	maxTextSize = 255
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string // the unquoted value for strings
+	raw  string // as written, for error messages
+	pos  int
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", raw: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", raw: ")", pos: i})
+			i++
+		case r == ':' || r == '=':
+			tokens = append(tokens, token{kind: tokOp, text: string(r), raw: string(r), pos: i})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(input) && input[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &Error{Pos: i, Token: op, Message: "expected !="}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, raw: op, pos: i})
+			i += len(op)
+		case r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(input) && input[j] != '"'; j++ {
+				if input[j] == '\\' && j+1 < len(input) {
+					j++
+				}
+				sb.WriteByte(input[j])
+			}
+			if j >= len(input) {
+				return nil, &Error{Pos: i, Token: input[i:], Message: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), raw: input[i : j+1], pos: i})
+			i = j + 1
+		default:
+			j := i
+			for j < len(input) {
+				r, size := utf8.DecodeRuneInString(input[j:])
+				if unicode.IsSpace(r) || strings.ContainsRune(`():=!<>"`, r) {
+					break
+				}
+				j += size
+			}
+			tokens = append(tokens, token{kind: tokWord, text: input[i:j], raw: input[i:j], pos: i})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+	fields Fields
+	terms  int
+	depth  int
+}
+
+// Parse parses input and checks it against fields. Terms are combined with
+// AND, OR and NOT (in order of increasing precedence: OR, AND, NOT) and
+// grouped with parentheses. An empty input yields a nil Expr.
+func Parse(input string, fields Fields) (Expr, error) {
+	if len(input) > MaxLength {
+		return nil, &Error{Pos: MaxLength, Token: input[MaxLength:min(len(input), MaxLength+10)], Message: "filter is too long"}
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorAt(tok, "unexpected token")
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) errorAt(tok token, message string) error {
+	return &Error{Pos: tok.pos, Token: tok.raw, Message: message}
+}
+
+func (p *parser) isKeyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokWord && strings.EqualFold(tok.text, word)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND") {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	tok := p.peek()
+
+	if p.isKeyword("NOT") || p.isKeyword("AND") || p.isKeyword("OR") {
+		if !p.isKeyword("NOT") {
+			return nil, p.errorAt(tok, "expected a field")
+		}
+		p.advance()
+		if err := p.enter(tok); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: inner}, nil
+	}
+
+	if tok.kind == tokLParen {
+		p.advance()
+		if err := p.enter(tok); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokRParen {
+			return nil, p.errorAt(closing, "expected )")
+		}
+		p.advance()
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) enter(tok token) error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return p.errorAt(tok, "filter is nested too deeply")
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	fieldTok := p.advance()
+	if fieldTok.kind != tokWord {
+		return nil, p.errorAt(fieldTok, "expected a field")
+	}
+
+	name := strings.ToLower(fieldTok.text)
+	field, ok := p.fields[name]
+	if !ok {
+		return nil, p.errorAt(fieldTok, "unknown field")
+	}
+
+	p.terms++
+	if p.terms > MaxTerms {
+		return nil, p.errorAt(fieldTok, "filter has too many terms")
+	}
+
+	opTok := p.advance()
+	if opTok.kind != tokOp {
+		return nil, p.errorAt(opTok, "expected an operator after "+name)
+	}
+	op := Op(opTok.text)
+
+	valueTok := p.advance()
+	if valueTok.kind != tokWord && valueTok.kind != tokString {
+		return nil, p.errorAt(valueTok, "expected a value")
+	}
+
+	value, err := convertValue(field, op, valueTok)
+	if err != nil {
+		return nil, p.errorAt(valueTok, err.Error())
+	}
+
+	return Comparison{Field: name, Op: op, Value: value, Pos: fieldTok.pos}, nil
+}
+
+type valueError string
+
+func (e valueError) Error() string {
+	return string(e)
+}
+
+func convertValue(field Field, op Op, tok token) (Value, error) {
+	text := tok.text
+	bare := tok.kind == tokWord
+
+	if bare && field.Nullable && (strings.EqualFold(text, "none") || strings.EqualFold(text, "null")) {
+		if op != OpMatch && op != OpEqual && op != OpNotEqual {
+			return Value{}, valueError("none can only be compared with :, = or !=")
+		}
+		return Value{Kind: KindNull}, nil
+	}
+
+	switch field.Type {
+	case Bool:
+		if op != OpMatch && op != OpEqual && op != OpNotEqual {
+			return Value{}, valueError("operator " + string(op) + " does not apply to true/false fields")
+		}
+		b, err := strconv.ParseBool(text)
+		if err != nil || !bare {
+			return Value{}, valueError("expected true or false")
+		}
+		return Value{Kind: KindBool, Bool: b}, nil
+
+	case Number, User:
+		if op != OpMatch && op != OpEqual && op != OpNotEqual {
+			return Value{}, valueError("operator " + string(op) + " does not apply to ID fields")
+		}
+		if field.Type == User && bare && strings.EqualFold(text, "me") {
+			return Value{Kind: KindMe}, nil
+		}
+		// IDs are 32-bit in the database; a larger number would only fail there
+		n, err := strconv.ParseInt(text, 10, 32)
+		if errors.Is(err, strconv.ErrRange) {
+			return Value{}, valueError("number is out of range")
+		}
+		if err != nil {
+			if field.Type == User {
+				return Value{}, valueError("expected a user ID or me")
+			}
+			return Value{}, valueError("expected a number")
+		}
+		return Value{Kind: KindNumber, Number: int(n)}, nil
+
+	case Text:
+		if op != OpMatch && op != OpEqual && op != OpNotEqual {
+			return Value{}, valueError("operator " + string(op) + " does not apply to text fields")
+		}
+		if len(text) > maxTextSize {
+			return Value{}, valueError("value is too long")
+		}
+		return Value{Kind: KindText, Text: text}, nil
+
+	case Time:
+		t, err := parseTime(text)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Kind: KindTime, Time: t}, nil
+	}
+
+	return Value{}, valueError("unsupported field")
+}
+
+// parseTime accepts dates (2026-11-01), RFC 3339 times, and times relative to
+// when the filter runs: now, today, tomorrow, yesterday and offsets such as
+// -7d, +2w (whole days from today) or -12h, 30m (from now).
+func parseTime(text string) (TimeValue, error) {
+	switch strings.ToLower(text) {
+	case "now":
+		return TimeValue{Relative: true}, nil
+	case "today":
+		return TimeValue{Relative: true, DateOnly: true}, nil
+	case "tomorrow":
+		return TimeValue{Relative: true, DateOnly: true, Days: 1}, nil
+	case "yesterday":
+		return TimeValue{Relative: true, DateOnly: true, Days: -1}, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", text); err == nil {
+		return TimeValue{Absolute: t, DateOnly: true}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return TimeValue{Absolute: t.UTC()}, nil
+	}
+
+	if len(text) >= 2 {
+		n, err := strconv.Atoi(strings.TrimPrefix(text[:len(text)-1], "+"))
+		if err == nil && n > -100000 && n < 100000 {
+			switch text[len(text)-1] {
+			case 'd':
+				return TimeValue{Relative: true, DateOnly: true, Days: n}, nil
+			case 'w':
+				return TimeValue{Relative: true, DateOnly: true, Days: 7 * n}, nil
+			case 'h':
+				return TimeValue{Relative: true, Duration: time.Duration(n) * time.Hour}, nil
+			case 'm':
+				return TimeValue{Relative: true, Duration: time.Duration(n) * time.Minute}, nil
+			}
+		}
+	}
+
+	return TimeValue{}, valueError("expected a date (2026-11-01), an RFC 3339 time, today, now or an offset like -7d")
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+// maxBatchBodyBytes caps the body of a whole batch.
+const maxBatchBodyBytes = 1 << 20
+
+// BatchHandler runs the sub-requests of a batch through the API's own router,
+// so each one goes through the same middleware (rate limiting, auth,
+// workspace) and handler as if it had been sent on its own.
+type BatchHandler struct {
+	router    http.Handler
+	validator *validator.Validate
+}
+
+func NewBatchHandler(router http.Handler) *BatchHandler {
+	return &BatchHandler{
+		router:    router,
+		validator: validator.New(),
+	}
+}
+
+var (
+	batchNamePattern      = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	batchReferencePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_-]+)\.([^}]+)\}`)
+
+	errUnresolvedReference = errors.New("unresolved reference")
+)
+
+// batchRecorder collects a sub-request's response.
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: http.Header{}}
+}
+
+func (w *batchRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *batchRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *batchRecorder) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func (w *batchRecorder) response(name string) models.BatchSubResponse {
+	response := models.BatchSubResponse{Name: name, Status: w.status}
+	if response.Status == 0 {
+		response.Status = http.StatusOK
+	}
+
+	if len(w.header) > 0 {
+		response.Headers = make(map[string]string, len(w.header))
+		for key := range w.header {
+			response.Headers[key] = w.header.Get(key)
+		}
+	}
+
+	body := bytes.TrimSpace(w.body.Bytes())
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		response.Body = json.RawMessage(body)
+	default:
+		response.Body, _ = json.Marshal(string(body))
+	}
+	return response
+}
+
+// batchError is the response of a sub-request the batch refused to send.
+func batchError(name string, status int, message string, details ...string) models.BatchSubResponse {
+	rec := newBatchRecorder()
+	utils.RespondError(rec, status, message, details...)
+	return rec.response(name)
+}
+
+// lookupReference finds path (fields and array indexes separated by dots) in
+// the body of the earlier sub-request called name.
+func lookupReference(results map[string]any, name, path string) (any, error) {
+	value, ok := results[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: no earlier successful sub-request is named %q", errUnresolvedReference, name)
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			value, ok = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			ok = err == nil && i >= 0 && i < len(v)
+			if ok {
+				value = v[i]
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s has no %s", errUnresolvedReference, name, path)
+		}
+	}
+	return value, nil
+}
+
+// referenceText is how a referenced value reads inside a string.
+func referenceText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	text, _ := json.Marshal(value)
+	return string(text)
+}
+
+// resolveText replaces every reference in s, passing the text of each through escape.
+func resolveText(s string, results map[string]any, escape func(string) string) (string, error) {
+	var err error
+	resolved := batchReferencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := batchReferencePattern.FindStringSubmatch(ref)
+		value, lookupErr := lookupReference(results, m[1], m[2])
+		if lookupErr != nil {
+			if err == nil {
+				err = lookupErr
+			}
+			return ref
+		}
+		return escape(referenceText(value))
+	})
+	return resolved, err
+}
+
+func resolveValue(value any, results map[string]any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if m := batchReferencePattern.FindStringSubmatch(v); m != nil && m[0] == v {
+			return lookupReference(results, m[1], m[2])
+		}
+		return resolveText(v, results, func(s string) string { return s })
+	case map[string]any:
+		for key, item := range v {
+			resolved, err := resolveValue(item, results)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []any:
+		for i, item := range v {
+			resolved, err := resolveValue(item, results)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return value, nil
+}
+
+// resolveBody replaces the references in the strings of a JSON body.
+func resolveBody(body json.RawMessage, results map[string]any) ([]byte, error) {
+	if len(body) == 0 || !batchReferencePattern.Match(body) {
+		return body, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	resolved, err := resolveValue(value, results)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+// dispatch sends one sub-request through the router as the caller.
+func (h *BatchHandler) dispatch(r *http.Request, sub models.BatchSubRequest, results map[string]any) models.BatchSubResponse {
+	path, err := resolveText(sub.Path, results, url.PathEscape)
+	if err != nil {
+		return batchError(sub.Name, http.StatusFailedDependency, "Unresolved reference", err.Error())
+	}
+	body, err := resolveBody(sub.Body, results)
+	if err != nil {
+		if errors.Is(err, errUnresolvedReference) {
+			return batchError(sub.Name, http.StatusFailedDependency, "Unresolved reference", err.Error())
+		}
+		return batchError(sub.Name, http.StatusBadRequest, "Invalid request body")
+	}
+
+	target, err := url.Parse(path)
+	if err != nil || target.IsAbs() || target.Host != "" {
+		return batchError(sub.Name, http.StatusBadRequest, "Invalid path")
+	}
+	if strings.TrimSuffix(target.Path, "/") == "/batch" {
+		return batchError(sub.Name, http.StatusBadRequest, "Batches cannot be nested")
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), sub.Method, target.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		return batchError(sub.Name, http.StatusBadRequest, "Invalid sub-request")
+	}
+	for key, value := range sub.Headers {
+		req.Header.Set(key, value)
+	}
+	if len(body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.Header.Get(middleware.WorkspaceHeader) == "" && r.Header.Get(middleware.WorkspaceHeader) != "" {
+		req.Header.Set(middleware.WorkspaceHeader, r.Header.Get(middleware.WorkspaceHeader))
+	}
+	req.Header.Set("Authorization", r.Header.Get("Authorization"))
+	req.RemoteAddr = r.RemoteAddr
+
+	rec := newBatchRecorder()
+	h.router.ServeHTTP(rec, req)
+	return rec.response(sub.Name)
+}
+
+// Batch runs up to models.MaxBatchRequests API calls in order and returns
+// their responses in the same order. A sub-request failing doesn't stop the
+// ones after it, but those referring to its result fail with 424.
+func (h *BatchHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.GetUserFromContext(r.Context()); !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid batch",
+			fmt.Sprintf("give 1 to %d requests, each with a method and a path starting with /", models.MaxBatchRequests))
+		return
+	}
+
+	names := map[string]bool{}
+	for _, sub := range req.Requests {
+		if sub.Name != "" {
+			if !batchNamePattern.MatchString(sub.Name) || names[sub.Name] {
+				utils.RespondError(w, http.StatusBadRequest, "Invalid batch",
+					"names must be unique and use only letters, digits, _ and -")
+				return
+			}
+			names[sub.Name] = true
+		}
+		for key := range sub.Headers {
+			if http.CanonicalHeaderKey(key) == "Authorization" {
+				utils.RespondError(w, http.StatusBadRequest, "Invalid batch", "sub-requests use the batch's Authorization")
+				return
+			}
+		}
+	}
+
+	// Bodies of earlier named sub-requests that succeeded, for references
+	results := map[string]any{}
+	responses := make([]models.BatchSubResponse, 0, len(req.Requests))
+	for _, sub := range req.Requests {
+		response := h.dispatch(r, sub, results)
+		responses = append(responses, response)
+
+		if sub.Name != "" && response.Status < http.StatusBadRequest && len(response.Body) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(response.Body))
+			decoder.UseNumber()
+			var body any
+			if err := decoder.Decode(&body); err == nil {
+				results[sub.Name] = body
+			}
+		}
+	}
+
+	utils.RespondJSON(w, http.StatusOK, responses)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestResolveBatchReferences(t *testing.T) {
+	var created any
+	if err := json.Unmarshal([]byte(`{"id": 42, "title": "Buy milk", "blocked_by": [7, 9]}`), &created); err != nil {
+		t.Fatal(err)
+	}
+	results := map[string]any{"created": created}
+
+	t.Run("whole string takes the field's type", func(t *testing.T) {
+		body, err := resolveBody(json.RawMessage(`{"blocker_id": "${created.id}", "note": "after ${created.title}"}`), results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"blocker_id":42,"note":"after Buy milk"}`
+		if string(body) != want {
+			t.Errorf("got %s, want %s", body, want)
+		}
+	})
+
+	t.Run("array elements", func(t *testing.T) {
+		path, err := resolveText("/todos/${created.blocked_by.1}/dependencies", results, func(s string) string { return s })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != "/todos/9/dependencies" {
+			t.Errorf("got %s", path)
+		}
+	})
+
+	t.Run("unknown references fail", func(t *testing.T) {
+		for _, ref := range []string{"/todos/${missing.id}", "/todos/${created.nope}", "/todos/${created.blocked_by.5}"} {
+			if _, err := resolveText(ref, results, func(s string) string { return s }); !errors.Is(err, errUnresolvedReference) {
+				t.Errorf("%s: got %v, want errUnresolvedReference", ref, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type CommentHandler struct {
+	commentRepo repository.Comment_Repository
+	todoRepo    repository.Todo_Repository
+	validator   *validator.Validate
+}
+
+func NewCommentHandler(commentRepo repository.Comment_Repository, todoRepo repository.Todo_Repository) *CommentHandler {
+	return &CommentHandler{
+		commentRepo: commentRepo,
+		todoRepo:    todoRepo,
+		validator:   validator.New(),
+	}
+}
+
+// loadTodo resolves the {id} route variable to a todo the caller may discuss.
+// It writes the error response itself and returns nil when the request should stop.
+func (h *CommentHandler) loadTodo(w http.ResponseWriter, r *http.Request, claims *models.Claims) *models.Todo {
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return nil
+	}
+
+	// The owner and every collaborator the todo is shared with may take part in the thread
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return nil
+	}
+
+	return todo
+}
+
+// loadComment resolves the {commentId} route variable and makes sure it belongs to the todo.
+func (h *CommentHandler) loadComment(w http.ResponseWriter, r *http.Request, todo *models.Todo, claims *models.Claims) *models.Comment {
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentId"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid comment ID")
+		return nil
+	}
+
+	comment, err := h.commentRepo.GetByID(r.Context(), commentID, claims.UserID)
+	if err != nil || comment.TodoID != todo.ID {
+		utils.RespondError(w, http.StatusNotFound, "Comment not found")
+		return nil
+	}
+
+	return comment
+}
+
+func (h *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todo := h.loadTodo(w, r, claims)
+	if todo == nil {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	comments, total, err := h.commentRepo.ListByTodoID(r.Context(), todo.ID, claims.UserID, page, limit)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch comments")
+		return
+	}
+
+	response := models.CommentListResponse{
+		Data:  comments,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todo := h.loadTodo(w, r, claims)
+	if todo == nil {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Comment body is required and must be at most 10000 characters")
+		return
+	}
+
+	comment := &models.Comment{
+		TodoID: todo.ID,
+		UserID: claims.UserID,
+		Author: models.CommentAuthor{ID: claims.UserID},
+		Body:   req.Body,
+	}
+
+	if err := h.commentRepo.Create(r.Context(), comment); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to create comment")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, comment)
+}
+
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todo := h.loadTodo(w, r, claims)
+	if todo == nil {
+		return
+	}
+
+	comment := h.loadComment(w, r, todo, claims)
+	if comment == nil {
+		return
+	}
+
+	// Only the author can edit their own words
+	if comment.UserID != claims.UserID {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if comment.Deleted {
+		utils.RespondError(w, http.StatusNotFound, "Comment not found")
+		return
+	}
+
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Comment body is required and must be at most 10000 characters")
+		return
+	}
+
+	comment.Body = req.Body
+	if err := h.commentRepo.Update(r.Context(), comment); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to update comment")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, comment)
+}
+
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todo := h.loadTodo(w, r, claims)
+	if todo == nil {
+		return
+	}
+
+	comment := h.loadComment(w, r, todo, claims)
+	if comment == nil {
+		return
+	}
+
+	// The author can remove their comment, and the todo's owners can moderate the thread
+	if comment.UserID != claims.UserID && !models.RoleAtLeast(todo.Role, models.RoleOwner) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if err := h.commentRepo.Delete(r.Context(), comment.ID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Comment not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
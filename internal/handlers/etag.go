@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+// todoETag is the strong entity tag of a todo: its quoted version. It changes
+// with every change to the todo itself; fields derived from other todos, like
+// blocked_by, don't count.
+func todoETag(todo *models.Todo) string {
+	return strconv.Quote(strconv.Itoa(todo.Version))
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header names etag.
+// If-Match compares strongly, so weak tags never match it; If-None-Match
+// compares weakly.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[len("W/"):]
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch honors If-Match for a write to the todo. If the header names
+// another version it writes 412 with the current ETag and returns false.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, todo *models.Todo) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, todoETag(todo), false) {
+		return true
+	}
+
+	respondPreconditionFailed(w, todo)
+	return false
+}
+
+// respondPreconditionFailed writes the 412 for a write based on an outdated
+// version of the todo, with the todo's current ETag if it is known.
+func respondPreconditionFailed(w http.ResponseWriter, current *models.Todo) {
+	if current != nil {
+		w.Header().Set("ETag", todoETag(current))
+	}
+	utils.RespondError(w, http.StatusPreconditionFailed, "Precondition failed",
+		"the todo was changed by someone else; fetch it again and retry")
+}
+
+// respondVersionConflict is respondPreconditionFailed for a write the
+// repository refused with ErrVersionConflict: the todo changed between reading
+// and saving it, so it is read again for its current ETag.
+func (h *TodoHandler) respondVersionConflict(w http.ResponseWriter, r *http.Request, todoID, userID int) {
+	current, _ := h.todoRepo.GetByID(r.Context(), todoID, userID) // nil if it is gone by now
+	respondPreconditionFailed(w, current)
+}
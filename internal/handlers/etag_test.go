@@ -0,0 +1,25 @@
+package handlers
+
+import "testing"
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"3"`, false, true},
+		{`"2"`, false, false},
+		{`"1", "3"`, false, true},
+		{`*`, false, true},
+		{`W/"3"`, false, false},
+		{`W/"3"`, true, true},
+		{`"30"`, true, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"3"`, tt.weak); got != tt.want {
+			t.Errorf("etagMatches(%s, weak=%v) = %v, want %v", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type NotificationHandler struct {
+	notificationRepo repository.Notification_Repository
+}
+
+func NewNotificationHandler(notificationRepo repository.Notification_Repository) *NotificationHandler {
+	return &NotificationHandler{notificationRepo: notificationRepo}
+}
+
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, total, err := h.notificationRepo.GetByUserID(r.Context(), claims.UserID, page, limit, unreadOnly)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+
+	response := models.NotificationListResponse{
+		Data:  notifications,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+func (h *NotificationHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	notificationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	if err := h.notificationRepo.MarkRead(r.Context(), notificationID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type ProjectHandler struct {
+	projectRepo repository.Project_Repository
+	validator   *validator.Validate
+}
+
+func NewProjectHandler(projectRepo repository.Project_Repository) *ProjectHandler {
+	return &ProjectHandler{
+		projectRepo: projectRepo,
+		validator:   validator.New(),
+	}
+}
+
+func (h *ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	projects, err := h.projectRepo.GetByUserID(r.Context(), claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch projects")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, projects)
+}
+
+func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	project := &models.Project{
+		UserID: claims.UserID,
+		Name:   req.Name,
+		Role:   models.RoleOwner,
+	}
+
+	if err := h.projectRepo.Create(r.Context(), project); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to create project")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, project)
+}
+
+func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+
+	project, err := h.projectRepo.GetByID(r.Context(), projectID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, project)
+}
+
+func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+
+	project, err := h.projectRepo.GetByID(r.Context(), projectID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	if !models.RoleAtLeast(project.Role, models.RoleEditor) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var req models.ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	project.Name = req.Name
+	if err := h.projectRepo.Update(r.Context(), project, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to update project")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, project)
+}
+
+func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid project ID")
+		return
+	}
+
+	if err := h.projectRepo.Delete(r.Context(), projectID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
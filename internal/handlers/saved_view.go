@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/filter"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type SavedViewHandler struct {
+	viewRepo  repository.SavedView_Repository
+	todoRepo  repository.Todo_Repository
+	validator *validator.Validate
+}
+
+func NewSavedViewHandler(viewRepo repository.SavedView_Repository, todoRepo repository.Todo_Repository) *SavedViewHandler {
+	return &SavedViewHandler{
+		viewRepo:  viewRepo,
+		todoRepo:  todoRepo,
+		validator: validator.New(),
+	}
+}
+
+// viewListOptions turns a view into the options of the todo listing it stands for.
+// Views run over everything the user can see; their filter narrows it down.
+func viewListOptions(view *models.SavedView) (models.TodoListOptions, error) {
+	expr, err := filter.Parse(view.Filter, models.TodoFilterFields)
+	if err != nil {
+		return models.TodoListOptions{}, err
+	}
+
+	sort, err := parseTodoSort(view.Sort, false)
+	if err != nil {
+		return models.TodoListOptions{}, err
+	}
+
+	return models.TodoListOptions{
+		Scope:  models.ScopeAll,
+		Sort:   sort,
+		Filter: expr,
+	}, nil
+}
+
+// loadView resolves {id}, which is a user view's ID or a system view's key,
+// writing the error response if there is no such view.
+func (h *SavedViewHandler) loadView(w http.ResponseWriter, r *http.Request, userID int) (*models.SavedView, bool) {
+	id := mux.Vars(r)["id"]
+
+	viewID, err := strconv.Atoi(id)
+	if err != nil {
+		view, ok := models.SystemView(id)
+		if !ok {
+			utils.RespondError(w, http.StatusNotFound, "View not found")
+			return nil, false
+		}
+		return &view, true
+	}
+
+	view, err := h.viewRepo.GetByID(r.Context(), viewID, userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "View not found")
+		return nil, false
+	}
+	return view, true
+}
+
+// GetViews lists the system views followed by the user's own, each with the
+// number of todos it currently matches.
+func (h *SavedViewHandler) GetViews(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userViews, err := h.viewRepo.GetByUserID(r.Context(), claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch views")
+		return
+	}
+
+	views := make([]models.SavedView, 0, len(models.SystemViews)+len(userViews))
+	for _, view := range models.SystemViews {
+		view.System = true
+		views = append(views, view)
+	}
+	views = append(views, userViews...)
+
+	// Count all views at once; one whose filter no longer parses gets no count
+	var counted []int
+	var opts []models.TodoListOptions
+	for i := range views {
+		viewOpts, err := viewListOptions(&views[i])
+		if err != nil {
+			continue
+		}
+		counted = append(counted, i)
+		opts = append(opts, viewOpts)
+	}
+
+	counts, err := h.todoRepo.CountByUserID(r.Context(), claims.UserID, opts)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch views")
+		return
+	}
+	for j, i := range counted {
+		views[i].Count = &counts[j]
+	}
+
+	utils.RespondJSON(w, http.StatusOK, views)
+}
+
+func (h *SavedViewHandler) GetView(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	view, ok := h.loadView(w, r, claims.UserID)
+	if !ok {
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, view)
+}
+
+// viewValidationDetail names the first field of a view request that failed
+// validation and what is wrong with it.
+func viewValidationDetail(err error) string {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) || len(fieldErrs) == 0 {
+		return err.Error()
+	}
+
+	fieldErr := fieldErrs[0]
+	field := strings.ToLower(fieldErr.Field())
+	if fieldErr.Tag() == "required" {
+		return field + " is required"
+	}
+	return fmt.Sprintf("%s must be at most %s characters", field, fieldErr.Param())
+}
+
+// decodeViewRequest reads and validates a view, including its filter expression,
+// writing the error response if it is invalid.
+func (h *SavedViewHandler) decodeViewRequest(w http.ResponseWriter, r *http.Request) (*models.SavedViewRequest, bool) {
+	var req models.SavedViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Validation failed", viewValidationDetail(err))
+		return nil, false
+	}
+
+	if _, err := parseTodoSort(req.Sort, false); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid sort", err.Error())
+		return nil, false
+	}
+
+	if _, err := filter.Parse(req.Filter, models.TodoFilterFields); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid filter", err.Error())
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func (h *SavedViewHandler) CreateView(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	req, ok := h.decodeViewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	view := &models.SavedView{
+		UserID: claims.UserID,
+		Name:   req.Name,
+		Filter: req.Filter,
+		Sort:   req.Sort,
+	}
+
+	if err := h.viewRepo.Create(r.Context(), view); err != nil {
+		if errors.Is(err, repository.ErrDuplicateViewName) {
+			utils.RespondError(w, http.StatusConflict, "A view with this name already exists")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to create view")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, view)
+}
+
+func (h *SavedViewHandler) UpdateView(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	viewID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+
+	view, err := h.viewRepo.GetByID(r.Context(), viewID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "View not found")
+		return
+	}
+
+	req, ok := h.decodeViewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	view.Name = req.Name
+	view.Filter = req.Filter
+	view.Sort = req.Sort
+
+	if err := h.viewRepo.Update(r.Context(), view); err != nil {
+		if errors.Is(err, repository.ErrDuplicateViewName) {
+			utils.RespondError(w, http.StatusConflict, "A view with this name already exists")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to update view")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, view)
+}
+
+func (h *SavedViewHandler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	viewID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+
+	if err := h.viewRepo.Delete(r.Context(), viewID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "View not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetViewTodos lists the todos a view matches, paginated like GET /todos.
+func (h *SavedViewHandler) GetViewTodos(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	view, ok := h.loadView(w, r, claims.UserID)
+	if !ok {
+		return
+	}
+
+	opts, err := viewListOptions(view)
+	if err != nil {
+		utils.RespondError(w, http.StatusUnprocessableEntity, "The view's filter or sort is no longer valid", err.Error())
+		return
+	}
+	todoPaging(r, &opts)
+
+	respondTodoList(w, r, h.todoRepo, claims.UserID, opts)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+func TestViewValidationDetail(t *testing.T) {
+	tests := []struct {
+		req  models.SavedViewRequest
+		want string
+	}{
+		{models.SavedViewRequest{}, "name is required"},
+		{models.SavedViewRequest{Name: "Mine", Filter: strings.Repeat("a", 1001)}, "filter must be at most 1000 characters"},
+		{models.SavedViewRequest{Name: "Mine", Sort: strings.Repeat("a", 101)}, "sort must be at most 100 characters"},
+	}
+
+	v := validator.New()
+	for _, tt := range tests {
+		if got := viewValidationDetail(v.Struct(tt.req)); got != tt.want {
+			t.Errorf("viewValidationDetail(%+v) = %q, want %q", tt.req, got, tt.want)
+		}
+	}
+}
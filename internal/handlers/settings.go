@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type SettingsHandler struct {
+	settingsRepo repository.Settings_Repository
+	validator    *validator.Validate
+}
+
+func NewSettingsHandler(settingsRepo repository.Settings_Repository) *SettingsHandler {
+	return &SettingsHandler{
+		settingsRepo: settingsRepo,
+		validator:    validator.New(),
+	}
+}
+
+func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	settings, err := h.settingsRepo.Get(r.Context(), claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch settings")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, settings)
+}
+
+func (h *SettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.UserSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Validation failed")
+		return
+	}
+
+	if err := h.settingsRepo.Update(r.Context(), claims.UserID, &req); err != nil {
+		if errors.Is(err, repository.ErrUnsupportedSearchLanguage) {
+			utils.RespondError(w, http.StatusBadRequest, "Unsupported search language")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to update settings")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, req)
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/tenant"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type ShareHandler struct {
+	shareRepo     repository.Share_Repository
+	todoRepo      repository.Todo_Repository
+	projectRepo   repository.Project_Repository
+	userRepo      repository.User_Repository
+	workspaceRepo repository.Workspace_Repository
+	validator     *validator.Validate
+}
+
+func NewShareHandler(shareRepo repository.Share_Repository, todoRepo repository.Todo_Repository, projectRepo repository.Project_Repository, userRepo repository.User_Repository, workspaceRepo repository.Workspace_Repository) *ShareHandler {
+	return &ShareHandler{
+		shareRepo:     shareRepo,
+		todoRepo:      todoRepo,
+		projectRepo:   projectRepo,
+		userRepo:      userRepo,
+		workspaceRepo: workspaceRepo,
+		validator:     validator.New(),
+	}
+}
+
+func (h *ShareHandler) ShareTodo(w http.ResponseWriter, r *http.Request) {
+	h.createShare(w, r, models.ShareTypeTodo)
+}
+
+func (h *ShareHandler) ShareProject(w http.ResponseWriter, r *http.Request) {
+	h.createShare(w, r, models.ShareTypeProject)
+}
+
+func (h *ShareHandler) GetTodoShares(w http.ResponseWriter, r *http.Request) {
+	h.listShares(w, r, models.ShareTypeTodo)
+}
+
+func (h *ShareHandler) GetProjectShares(w http.ResponseWriter, r *http.Request) {
+	h.listShares(w, r, models.ShareTypeProject)
+}
+
+func (h *ShareHandler) RevokeTodoShare(w http.ResponseWriter, r *http.Request) {
+	h.revokeShare(w, r, models.ShareTypeTodo)
+}
+
+func (h *ShareHandler) RevokeProjectShare(w http.ResponseWriter, r *http.Request) {
+	h.revokeShare(w, r, models.ShareTypeProject)
+}
+
+// resolveRole parses the {id} route variable and returns the caller's role on that
+// todo or project. It writes the error response itself and returns ok=false on failure.
+func (h *ShareHandler) resolveRole(w http.ResponseWriter, r *http.Request, resourceType string, userID int) (int, string, bool) {
+	resourceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid "+resourceType+" ID")
+		return 0, "", false
+	}
+
+	if resourceType == models.ShareTypeTodo {
+		todo, err := h.todoRepo.GetByID(r.Context(), resourceID, userID)
+		if err != nil {
+			utils.RespondError(w, http.StatusNotFound, "Todo not found")
+			return 0, "", false
+		}
+		return resourceID, todo.Role, true
+	}
+
+	project, err := h.projectRepo.GetByID(r.Context(), resourceID, userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Project not found")
+		return 0, "", false
+	}
+	return resourceID, project.Role, true
+}
+
+func (h *ShareHandler) createShare(w http.ResponseWriter, r *http.Request, resourceType string) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resourceID, role, ok := h.resolveRole(w, r, resourceType, claims.UserID)
+	if !ok {
+		return
+	}
+
+	// Only owners may hand out access
+	if !models.RoleAtLeast(role, models.RoleOwner) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var req models.ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "A valid email and a role of viewer, editor or owner are required")
+		return
+	}
+
+	invitee, err := h.userRepo.GetByEmail(r.Context(), strings.ToLower(req.Email))
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if invitee.ID == claims.UserID {
+		utils.RespondError(w, http.StatusBadRequest, "You cannot share with yourself")
+		return
+	}
+
+	// Sharing never crosses the workspace boundary
+	workspace, _ := tenant.FromContext(r.Context())
+	if _, err := h.workspaceRepo.GetMember(r.Context(), workspace.ID, invitee.ID); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "User is not a member of this workspace")
+		return
+	}
+
+	share := &models.Share{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		UserID:       invitee.ID,
+		Name:         invitee.Name,
+		Email:        invitee.Email,
+		Role:         req.Role,
+		InvitedBy:    claims.UserID,
+	}
+
+	if err := h.shareRepo.Invite(r.Context(), share); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to share "+resourceType)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, share)
+}
+
+func (h *ShareHandler) listShares(w http.ResponseWriter, r *http.Request, resourceType string) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Anyone with access may see who else is collaborating
+	resourceID, _, ok := h.resolveRole(w, r, resourceType, claims.UserID)
+	if !ok {
+		return
+	}
+
+	shares, err := h.shareRepo.ListByResource(r.Context(), resourceType, resourceID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch shares")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, shares)
+}
+
+func (h *ShareHandler) revokeShare(w http.ResponseWriter, r *http.Request, resourceType string) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	resourceID, role, ok := h.resolveRole(w, r, resourceType, claims.UserID)
+	if !ok {
+		return
+	}
+
+	shareID, err := strconv.Atoi(mux.Vars(r)["shareId"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid share ID")
+		return
+	}
+
+	share, err := h.shareRepo.GetByID(r.Context(), shareID)
+	if err != nil || share.ResourceType != resourceType || share.ResourceID != resourceID {
+		utils.RespondError(w, http.StatusNotFound, "Share not found")
+		return
+	}
+
+	// Owners can revoke anyone; everyone else can only leave
+	if share.UserID != claims.UserID && !models.RoleAtLeast(role, models.RoleOwner) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if err := h.shareRepo.Delete(r.Context(), share.ID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Share not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ShareHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invitations, err := h.shareRepo.ListPendingForUser(r.Context(), claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch invitations")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, invitations)
+}
+
+func (h *ShareHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondToInvitation(w, r, true)
+}
+
+func (h *ShareHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondToInvitation(w, r, false)
+}
+
+func (h *ShareHandler) respondToInvitation(w http.ResponseWriter, r *http.Request, accept bool) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	shareID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	share, err := h.shareRepo.Respond(r.Context(), shareID, claims.UserID, accept)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Invitation not found")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, share)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+// SharePasswordHeader carries the password of a protected share link.
+// A header rather than a query parameter keeps it out of access logs.
+const SharePasswordHeader = "X-Share-Password"
+
+type ShareLinkHandler struct {
+	shareLinkRepo repository.ShareLink_Repository
+	todoRepo      repository.Todo_Repository
+	projectRepo   repository.Project_Repository
+	validator     *validator.Validate
+}
+
+func NewShareLinkHandler(shareLinkRepo repository.ShareLink_Repository, todoRepo repository.Todo_Repository, projectRepo repository.Project_Repository) *ShareLinkHandler {
+	return &ShareLinkHandler{
+		shareLinkRepo: shareLinkRepo,
+		todoRepo:      todoRepo,
+		projectRepo:   projectRepo,
+		validator:     validator.New(),
+	}
+}
+
+func (h *ShareLinkHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.CreateShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Validation failed",
+			"resource_type must be todo or project, resource_id is required and a password must be at least 6 characters")
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		utils.RespondError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	// Only owners may publish what they own
+	var role string
+	if req.ResourceType == models.ShareTypeTodo {
+		todo, err := h.todoRepo.GetByID(r.Context(), req.ResourceID, claims.UserID)
+		if err != nil {
+			utils.RespondError(w, http.StatusNotFound, "Todo not found")
+			return
+		}
+		role = todo.Role
+	} else {
+		project, err := h.projectRepo.GetByID(r.Context(), req.ResourceID, claims.UserID)
+		if err != nil {
+			utils.RespondError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		role = project.Role
+	}
+
+	if !models.RoleAtLeast(role, models.RoleOwner) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to create share link")
+		return
+	}
+
+	link := &models.ShareLink{
+		UserID:       claims.UserID,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Token:        token,
+		TokenHash:    utils.HashToken(token),
+		ExpiresAt:    req.ExpiresAt,
+	}
+
+	if req.Password != "" {
+		hashedPassword, err := utils.HashPassword(req.Password)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to create share link")
+			return
+		}
+		link.PasswordHash = &hashedPassword
+	}
+
+	if err := h.shareLinkRepo.Create(r.Context(), link); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to create share link")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, link)
+}
+
+func (h *ShareLinkHandler) GetShareLinks(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	links, err := h.shareLinkRepo.GetByUserID(r.Context(), claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch share links")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, links)
+}
+
+func (h *ShareLinkHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	linkID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid share link ID")
+		return
+	}
+
+	if err := h.shareLinkRepo.Revoke(r.Context(), linkID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Share link not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSharedView is the unauthenticated endpoint behind a share link.
+// Unknown, revoked and expired links are indistinguishable to the caller.
+func (h *ShareLinkHandler) GetSharedView(w http.ResponseWriter, r *http.Request) {
+	link, err := h.shareLinkRepo.GetActiveByTokenHash(r.Context(), utils.HashToken(mux.Vars(r)["token"]))
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Share link not found")
+		return
+	}
+
+	if link.PasswordHash != nil {
+		password := r.Header.Get(SharePasswordHeader)
+		if password == "" || !utils.CheckPassword(password, *link.PasswordHash) {
+			utils.RespondError(w, http.StatusUnauthorized, "Password required", "send the link password in the "+SharePasswordHeader+" header")
+			return
+		}
+	}
+
+	view, err := h.shareLinkRepo.GetView(r.Context(), link)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Share link not found")
+		return
+	}
+
+	if err := h.shareLinkRepo.RecordAccess(r.Context(), link.ID); err != nil {
+		log.Printf("Error recording access to share link %d: %v", link.ID, err)
+	}
+
+	utils.RespondJSON(w, http.StatusOK, view)
+}
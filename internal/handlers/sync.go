@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type SyncHandler struct {
+	todos *TodoHandler
+	// tombstoneRetentionDays is how long deletions are kept for clients to
+	// sync; tokens older than that are refused
+	tombstoneRetentionDays int
+}
+
+func NewSyncHandler(todoHandler *TodoHandler, tombstoneRetentionDays int) *SyncHandler {
+	return &SyncHandler{
+		todos:                  todoHandler,
+		tombstoneRetentionDays: tombstoneRetentionDays,
+	}
+}
+
+var errUnknownSyncField = errors.New("unknown field")
+
+// sameJSONValue compares two JSON values regardless of formatting.
+func sameJSONValue(a, b json.RawMessage) bool {
+	var x, y any
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// mergeSyncFields settles the fields a client change sets against doc, the
+// todo's current models.TodoPatchDocument as JSON members. serverChanged
+// holds when each field last changed on the server after the change's base
+// version. A field only the client changed takes the client's value; one the
+// server changed too, to something else, is a conflict the resolution
+// decides. doc ends up with the fields the client won.
+func mergeSyncFields(doc, fields map[string]json.RawMessage, serverChanged map[string]time.Time, changedAt time.Time, resolution string) ([]models.SyncConflict, error) {
+	conflicts := []models.SyncConflict{}
+	for field, value := range fields {
+		current, ok := doc[field]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", errUnknownSyncField, field)
+		}
+
+		serverAt, changed := serverChanged[field]
+		if !changed || sameJSONValue(current, value) {
+			doc[field] = value
+			continue
+		}
+
+		winner := models.SyncWinnerServer
+		if resolution == models.SyncClientWins ||
+			(resolution == models.SyncLastWriterWins && changedAt.After(serverAt)) {
+			winner = models.SyncWinnerClient
+			doc[field] = value
+		}
+		conflicts = append(conflicts, models.SyncConflict{
+			Field:           field,
+			ClientValue:     value,
+			ServerValue:     current,
+			ServerChangedAt: serverAt,
+			Winner:          winner,
+		})
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Field < conflicts[j].Field })
+	return conflicts, nil
+}
+
+// syncPlan turns client changes into bulk writes, with the checks of a bulk
+// request; results are by change, as are the conflicts.
+type syncPlan struct {
+	*bulkPlan
+	resolution string
+	// fieldChanges are the server's changes to the todos the request names
+	// since the oldest base version of each
+	fieldChanges map[int][]models.TodoFieldChange
+	conflicts    [][]models.SyncConflict
+}
+
+// serverChanged is when each field of the todo last changed on the server
+// after version.
+func (p *syncPlan) serverChanged(id, version int) map[string]time.Time {
+	changed := map[string]time.Time{}
+	for _, change := range p.fieldChanges[id] {
+		if change.Version > version && change.ChangedAt.After(changed[change.Field]) {
+			changed[change.Field] = change.ChangedAt
+		}
+	}
+	return changed
+}
+
+// document merges the change into doc and decodes the result, writing the
+// change's failure if it isn't a valid todo.
+func (p *syncPlan) document(index int, change models.SyncChange, id *int, doc models.TodoPatchDocument, serverChanged map[string]time.Time) (*models.TodoPatchDocument, bool) {
+	data, _ := json.Marshal(doc)
+	members := map[string]json.RawMessage{}
+	json.Unmarshal(data, &members)
+
+	conflicts, err := mergeSyncFields(members, change.Fields, serverChanged, change.ChangedAt, p.resolution)
+	if err != nil {
+		p.fail(index, change.Op, id, http.StatusBadRequest, "Invalid fields: "+err.Error())
+		return nil, false
+	}
+	p.conflicts[index] = conflicts
+
+	data, _ = json.Marshal(members)
+	var result models.TodoPatchDocument
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		p.fail(index, change.Op, id, http.StatusUnprocessableEntity, "Invalid todo: "+err.Error())
+		return nil, false
+	}
+	if err := p.h.validator.Struct(result); err != nil {
+		p.fail(index, change.Op, id, http.StatusUnprocessableEntity, "Invalid todo: title is required and completed must be true or false")
+		return nil, false
+	}
+	return &result, true
+}
+
+func (p *syncPlan) create(index int, change models.SyncChange) error {
+	doc, ok := p.document(index, change, nil, models.PatchDocumentFor(&models.Todo{}), nil)
+	if !ok {
+		return nil
+	}
+
+	if doc.ProjectID != nil && !p.canFileInProject(*doc.ProjectID) {
+		p.fail(index, change.Op, nil, http.StatusForbidden, "You cannot add todos to this project")
+		return nil
+	}
+
+	todo := &models.Todo{
+		UserID:      p.claims.UserID,
+		ProjectID:   doc.ProjectID,
+		Title:       doc.Title,
+		Description: doc.Description,
+		Completed:   *doc.Completed,
+		Role:        models.RoleOwner,
+	}
+	if ok, err := p.placeItem(index, change.Op, todo, doc.StateID); !ok {
+		return err
+	}
+	if doc.AssigneeID != nil {
+		if ok, err := p.assign(index, change.Op, todo, doc.AssigneeID); !ok {
+			return err
+		}
+	}
+
+	p.write(index, change.Op, models.BulkWriteInsert, todo, nil, http.StatusCreated, nil)
+	return nil
+}
+
+func (p *syncPlan) update(index int, change models.SyncChange) error {
+	todo, ok := p.todo(index, change.Op, change.ID, models.RoleEditor)
+	if !ok {
+		return nil
+	}
+
+	original := models.PatchDocumentFor(todo)
+	doc, ok := p.document(index, change, &todo.ID, original, p.serverChanged(todo.ID, change.BaseVersion))
+	if !ok {
+		return nil
+	}
+	if reflect.DeepEqual(*doc, original) {
+		p.results = append(p.results, models.BulkItemResult{Operation: index, Op: change.Op, ID: &todo.ID, Status: http.StatusOK})
+		return nil
+	}
+
+	// As with PATCH, only what changed is checked again
+	wasCompleted, previous := todo.Completed, todo.AssigneeID
+	todo.Title, todo.Description, todo.Completed = doc.Title, doc.Description, *doc.Completed
+	if !p.moveToProject(index, change.Op, todo, doc.ProjectID) {
+		return nil
+	}
+	var stateID *int
+	if doc.StateID != nil && !sameID(doc.StateID, todo.StateID) {
+		stateID = doc.StateID
+	}
+	if ok, err := p.placeItem(index, change.Op, todo, stateID); !ok {
+		return err
+	}
+	if !sameID(doc.AssigneeID, todo.AssigneeID) {
+		if ok, err := p.assign(index, change.Op, todo, orZero(doc.AssigneeID)); !ok {
+			return err
+		}
+	}
+
+	warnings, ok, err := p.checkCompletion(index, change.Op, todo, wasCompleted)
+	if !ok {
+		return err
+	}
+
+	p.write(index, change.Op, models.BulkWriteUpdate, todo, previous, http.StatusOK, warnings)
+	return nil
+}
+
+// delete trashes the todo unless it changed on the server since the change's
+// base version and the resolution keeps the server's todo.
+func (p *syncPlan) delete(index int, change models.SyncChange) {
+	todo, ok := p.todo(index, change.Op, change.ID, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var serverAt time.Time
+	for _, at := range p.serverChanged(todo.ID, change.BaseVersion) {
+		if at.After(serverAt) {
+			serverAt = at
+		}
+	}
+	if !serverAt.IsZero() {
+		winner := models.SyncWinnerServer
+		if p.resolution == models.SyncClientWins ||
+			(p.resolution == models.SyncLastWriterWins && change.ChangedAt.After(serverAt)) {
+			winner = models.SyncWinnerClient
+		}
+		p.conflicts[index] = []models.SyncConflict{{
+			Field:           models.SyncDeletedField,
+			ClientValue:     json.RawMessage(`true`),
+			ServerValue:     json.RawMessage(`false`),
+			ServerChangedAt: serverAt,
+			Winner:          winner,
+		}}
+		if winner == models.SyncWinnerServer {
+			p.fail(index, change.Op, &todo.ID, http.StatusConflict, "The todo was changed on the server after it was deleted")
+			return
+		}
+	}
+
+	p.write(index, change.Op, models.BulkWriteTrash, todo, nil, http.StatusOK, nil)
+}
+
+func (p *syncPlan) change(index int, change models.SyncChange) error {
+	if change.Op != models.SyncCreate && change.ID == 0 {
+		p.fail(index, change.Op, nil, http.StatusBadRequest, "id is required")
+		return nil
+	}
+
+	switch change.Op {
+	case models.SyncCreate:
+		return p.create(index, change)
+	case models.SyncUpdate:
+		return p.update(index, change)
+	case models.SyncDelete:
+		p.delete(index, change)
+	}
+	return nil
+}
+
+// Sync applies a client's queued offline changes and returns what changed on
+// the server since its last sync. Each change is applied on its own, in
+// order: one that fails is reported and skipped. Fields changed both by the
+// client and on the server since the change's base version are settled per
+// field by the request's conflict_resolution and reported. The server's
+// changes come a page at a time; while has_more is set, the client syncs
+// again with the new token (and no changes) for the rest.
+func (h *SyncHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.todos.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid sync request", err.Error())
+		return
+	}
+	if req.ConflictResolution == "" {
+		req.ConflictResolution = models.SyncLastWriterWins
+	}
+
+	// The token is checked before anything is applied
+	notBefore := time.Now().AddDate(0, 0, -h.tombstoneRetentionDays)
+	if err := h.todos.todoRepo.CheckSyncToken(r.Context(), req.SyncToken, notBefore); err != nil {
+		h.respondSyncTokenError(w, err)
+		return
+	}
+
+	results := []models.SyncChangeResult{}
+	if len(req.Changes) > 0 {
+		var ok bool
+		if results, ok = h.apply(w, r, claims, req); !ok {
+			return
+		}
+	}
+
+	page, err := h.todos.todoRepo.SyncChanges(r.Context(), req.SyncToken, notBefore, models.SyncPageSize, claims.UserID)
+	if err != nil {
+		h.respondSyncTokenError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.SyncResponse{
+		Results:   results,
+		Todos:     page.Todos,
+		Deleted:   page.Deleted,
+		SyncToken: page.SyncToken,
+		HasMore:   page.HasMore,
+		Full:      page.Full,
+	})
+}
+
+func (h *SyncHandler) respondSyncTokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrInvalidSyncToken):
+		utils.RespondError(w, http.StatusBadRequest, "Invalid sync token")
+	case errors.Is(err, repository.ErrSyncTokenExpired):
+		utils.RespondError(w, http.StatusGone, "Sync token expired", "sync again without a token to get every todo")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to sync")
+	}
+}
+
+// apply applies the client's changes and reports each, writing the error
+// response if that fails as a whole.
+func (h *SyncHandler) apply(w http.ResponseWriter, r *http.Request, claims *models.Claims, req models.SyncRequest) ([]models.SyncChangeResult, bool) {
+	// One query checks the user's access to every todo the changes name, and
+	// one finds what changed on the server since the oldest base of each
+	ids := []int{}
+	bases := map[int]int{}
+	for _, change := range req.Changes {
+		if change.Op == models.SyncCreate || change.ID == 0 {
+			continue
+		}
+		if base, ok := bases[change.ID]; !ok || change.BaseVersion < base {
+			if !ok {
+				ids = append(ids, change.ID)
+			}
+			bases[change.ID] = change.BaseVersion
+		}
+	}
+
+	todos, err := h.todos.todoRepo.GetByIDs(r.Context(), ids, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to sync")
+		return nil, false
+	}
+	fieldChanges, err := h.todos.todoRepo.FieldChangesSince(r.Context(), bases, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to sync")
+		return nil, false
+	}
+
+	plan := &syncPlan{
+		bulkPlan: &bulkPlan{
+			h:        h.todos,
+			r:        r,
+			claims:   claims,
+			original: todos,
+			current:  make(map[int]*models.Todo, len(todos)),
+			canFile:  map[int]bool{},
+			states:   map[int][]models.WorkflowState{},
+			results:  []models.BulkItemResult{},
+		},
+		resolution:   req.ConflictResolution,
+		fieldChanges: fieldChanges,
+		conflicts:    make([][]models.SyncConflict, len(req.Changes)),
+	}
+	for id, todo := range todos {
+		plan.current[id] = todo
+	}
+
+	for i, change := range req.Changes {
+		if err := plan.change(i, change); err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to sync")
+			return nil, false
+		}
+	}
+
+	errs := make([]error, len(plan.writes))
+	if len(plan.writes) > 0 {
+		errs, err = h.todos.todoRepo.ApplyBulk(r.Context(), claims.UserID, plan.writes, false)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to sync")
+			return nil, false
+		}
+	}
+
+	for i, write := range plan.writes {
+		result := &plan.results[plan.writeResults[i]]
+		switch {
+		case errors.Is(errs[i], repository.ErrVersionConflict):
+			result.Status, result.Error = http.StatusPreconditionFailed, "The todo was changed while syncing; send the change again"
+		case errs[i] != nil:
+			result.Status, result.Error = http.StatusInternalServerError, "Failed to save todo"
+		default:
+			result.ID = &write.Todo.ID
+			if write.Op != models.BulkWriteTrash {
+				h.todos.notifyAssignment(r, write.Todo, plan.previous[i], claims)
+			}
+		}
+	}
+
+	results := make([]models.SyncChangeResult, len(plan.results))
+	for i, result := range plan.results {
+		change := req.Changes[result.Operation]
+		results[i] = models.SyncChangeResult{
+			ClientID:  change.ClientID,
+			Op:        change.Op,
+			ID:        result.ID,
+			Status:    result.Status,
+			Error:     result.Error,
+			Conflicts: plan.conflicts[result.Operation],
+			Warnings:  result.Warnings,
+		}
+	}
+	return results, true
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+func TestMergeSyncFields(t *testing.T) {
+	serverAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	before, after := serverAt.Add(-time.Minute), serverAt.Add(time.Minute)
+
+	tests := []struct {
+		name       string
+		changedAt  time.Time
+		resolution string
+		wantTitle  string
+		wantDesc   string
+		wantWinner string
+	}{
+		{"later client wins", after, models.SyncLastWriterWins, `"client"`, `"mine"`, models.SyncWinnerClient},
+		{"later server wins", before, models.SyncLastWriterWins, `"server"`, `"mine"`, models.SyncWinnerServer},
+		{"server wins", after, models.SyncServerWins, `"server"`, `"mine"`, models.SyncWinnerServer},
+		{"client wins", before, models.SyncClientWins, `"client"`, `"mine"`, models.SyncWinnerClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := map[string]json.RawMessage{
+				"title":       json.RawMessage(`"server"`),
+				"description": json.RawMessage(`""`),
+				"completed":   json.RawMessage(`true`),
+			}
+			fields := map[string]json.RawMessage{
+				"title":       json.RawMessage(`"client"`),
+				"description": json.RawMessage(`"mine"`),
+				"completed":   json.RawMessage(`true`),
+			}
+			// The server changed the title and completed it; the client only
+			// agrees on completed, and nobody else touched the description
+			serverChanged := map[string]time.Time{"title": serverAt, "completed": serverAt}
+
+			conflicts, err := mergeSyncFields(doc, fields, serverChanged, tt.changedAt, tt.resolution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(doc["title"]) != tt.wantTitle || string(doc["description"]) != tt.wantDesc {
+				t.Errorf("got title %s and description %s, want %s and %s", doc["title"], doc["description"], tt.wantTitle, tt.wantDesc)
+			}
+			if len(conflicts) != 1 || conflicts[0].Field != "title" || conflicts[0].Winner != tt.wantWinner {
+				t.Fatalf("got conflicts %+v, want one on title won by %s", conflicts, tt.wantWinner)
+			}
+			if string(conflicts[0].ServerValue) != `"server"` || string(conflicts[0].ClientValue) != `"client"` {
+				t.Errorf("got conflict values %s and %s", conflicts[0].ServerValue, conflicts[0].ClientValue)
+			}
+		})
+	}
+}
+
+func TestMergeSyncFieldsUnknownField(t *testing.T) {
+	doc := map[string]json.RawMessage{"title": json.RawMessage(`"a"`)}
+	fields := map[string]json.RawMessage{"owner": json.RawMessage(`1`)}
+
+	if _, err := mergeSyncFields(doc, fields, nil, time.Now(), models.SyncLastWriterWins); !errors.Is(err, errUnknownSyncField) {
+		t.Errorf("got %v, want %v", err, errUnknownSyncField)
+	}
+}
@@ -2,28 +2,158 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/filter"
 	"github.com/pigeio/todo-api/internal/middleware"
 	"github.com/pigeio/todo-api/internal/models"
 	"github.com/pigeio/todo-api/internal/repository" // Import for interface
 	"github.com/pigeio/todo-api/internal/utils"
 )
 
+// maxSearchQueryLength caps GET /todos?q= in bytes
+const maxSearchQueryLength = 200
+
 type TodoHandler struct {
 	// Use your interface name
-	todoRepo  repository.Todo_Repository
-	validator *validator.Validate
+	todoRepo         repository.Todo_Repository
+	projectRepo      repository.Project_Repository
+	notificationRepo repository.Notification_Repository
+	workflowRepo     repository.WorkflowState_Repository
+	settingsRepo     repository.Settings_Repository
+	undoRepo         repository.Undo_Repository
+	validator        *validator.Validate
 }
 
 // Use your interface name
-func NewTodoHandler(todoRepo repository.Todo_Repository) *TodoHandler {
+func NewTodoHandler(todoRepo repository.Todo_Repository, projectRepo repository.Project_Repository, notificationRepo repository.Notification_Repository, workflowRepo repository.WorkflowState_Repository, settingsRepo repository.Settings_Repository, undoRepo repository.Undo_Repository) *TodoHandler {
 	return &TodoHandler{
-		todoRepo:  todoRepo,
-		validator: validator.New(),
+		todoRepo:         todoRepo,
+		projectRepo:      projectRepo,
+		notificationRepo: notificationRepo,
+		workflowRepo:     workflowRepo,
+		settingsRepo:     settingsRepo,
+		undoRepo:         undoRepo,
+		validator:        validator.New(),
+	}
+}
+
+// canFileInProject reports whether the user may put todos into the project.
+func (h *TodoHandler) canFileInProject(r *http.Request, projectID, userID int) bool {
+	project, err := h.projectRepo.GetByID(r.Context(), projectID, userID)
+	return err == nil && models.RoleAtLeast(project.Role, models.RoleEditor)
+}
+
+// projectMoveError says why the user can't move the todo to projectID (nil
+// takes it out of its project), or is empty if they can. Moving a todo changes
+// who can reach it: an editor could otherwise take a todo into a project they
+// own and become its owner, or take it away from its project's members. So
+// only an owner of the todo (or of its project) may move it, and only into a
+// project they can add todos to.
+func projectMoveError(todo *models.Todo, projectID *int, canFile func(projectID int) bool) string {
+	if sameID(todo.ProjectID, projectID) {
+		return ""
+	}
+	if !models.RoleAtLeast(todo.Role, models.RoleOwner) {
+		return "Only an owner of the todo can move it to another project"
+	}
+	if projectID != nil && !canFile(*projectID) {
+		return "You cannot add todos to this project"
+	}
+	return ""
+}
+
+var errInvalidState = errors.New("invalid state")
+
+// placeInWorkflow keeps the todo's workflow state in line with its project and
+// completion, so clients that only know about completed keep working. A state
+// asked for explicitly wins and completes or reopens the todo to match;
+// otherwise a todo whose state isn't one of its project's, or disagrees with
+// its completion, goes to the first state that agrees.
+func (h *TodoHandler) placeInWorkflow(r *http.Request, todo *models.Todo, stateID *int, userID int) error {
+	var states []models.WorkflowState
+	if todo.ProjectID != nil {
+		var err error
+		if states, err = h.workflowRepo.ListByProject(r.Context(), *todo.ProjectID, userID); err != nil {
+			return err
+		}
+	}
+
+	return placeInStates(todo, states, stateID)
+}
+
+// placeInStates is placeInWorkflow with the states of the todo's project
+// already looked up.
+func placeInStates(todo *models.Todo, states []models.WorkflowState, stateID *int) error {
+	if todo.ProjectID == nil {
+		if stateID != nil {
+			return fmt.Errorf("%w: only todos in a project have a state", errInvalidState)
+		}
+		todo.StateID, todo.State = nil, nil
+		return nil
+	}
+
+	if stateID != nil {
+		for _, state := range states {
+			if state.ID == *stateID {
+				todo.StateID, todo.State = &state.ID, &state.Name
+				todo.Completed = state.IsDone
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: the state does not belong to the todo's project", errInvalidState)
+	}
+
+	if todo.StateID != nil {
+		for _, state := range states {
+			if state.ID == *todo.StateID && state.IsDone == todo.Completed {
+				return nil
+			}
+		}
+	}
+
+	todo.StateID, todo.State = nil, nil
+	if state := models.StateFor(states, todo.Completed); state != nil {
+		todo.StateID, todo.State = &state.ID, &state.Name
+	}
+	return nil
+}
+
+// notifyAssignment tells the new assignee about the todo, and the previous one that it
+// was taken off them. Nobody is notified about their own actions. Failures are only
+// logged; the todo has already been saved.
+func (h *TodoHandler) notifyAssignment(r *http.Request, todo *models.Todo, previous *int, actor *models.Claims) {
+	send := func(userID int, kind, message string) {
+		if userID == actor.UserID {
+			return
+		}
+		n := &models.Notification{
+			UserID:  userID,
+			Type:    kind,
+			TodoID:  &todo.ID,
+			ActorID: &actor.UserID,
+			Message: message,
+		}
+		if err := h.notificationRepo.Create(r.Context(), n); err != nil {
+			log.Printf("Error creating notification for user %d: %v", userID, err)
+		}
+	}
+
+	if previous != nil && (todo.AssigneeID == nil || *previous != *todo.AssigneeID) {
+		send(*previous, models.NotificationTodoUnassigned,
+			fmt.Sprintf("%s unassigned you from %q", actor.Email, todo.Title))
+	}
+	if todo.AssigneeID != nil && (previous == nil || *previous != *todo.AssigneeID) {
+		send(*todo.AssigneeID, models.NotificationTodoAssigned,
+			fmt.Sprintf("%s assigned you to %q", actor.Email, todo.Title))
 	}
 }
 
@@ -49,11 +179,54 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.ProjectID != nil && !h.canFileInProject(r, *req.ProjectID, claims.UserID) {
+		utils.RespondError(w, http.StatusForbidden, "You cannot add todos to this project")
+		return
+	}
+
 	// Create todo
 	todo := &models.Todo{
 		UserID:      claims.UserID,
+		ProjectID:   req.ProjectID,
 		Title:       req.Title,
 		Description: req.Description,
+		Role:        models.RoleOwner,
+	}
+
+	if err := h.placeInWorkflow(r, todo, req.StateID, claims.UserID); err != nil {
+		if errors.Is(err, errInvalidState) {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid state", err.Error())
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to create todo")
+		return
+	}
+
+	if req.AssigneeID != nil && *req.AssigneeID != 0 {
+		todo.AssigneeID = req.AssigneeID
+		allowed, err := h.todoRepo.CanBeAssigned(r.Context(), todo, *req.AssigneeID, claims.UserID)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to create todo")
+			return
+		}
+		if !allowed {
+			utils.RespondError(w, http.StatusBadRequest, "Assignee does not have access to this todo")
+			return
+		}
+	}
+
+	// Likely duplicates don't stop the todo from being created; the client
+	// decides whether to bother the user about them
+	response := models.CreateTodoResponse{Todo: todo}
+	duplicates, err := h.todoRepo.FindDuplicates(r.Context(), todo.Title, claims.UserID)
+	if err != nil {
+		log.Printf("Error looking for duplicates of %q: %v", todo.Title, err)
+	} else if len(duplicates) > 0 {
+		response.Warnings = append(response.Warnings, models.TodoWarning{
+			Code:       models.WarningPossibleDuplicate,
+			Message:    "A similar open todo already exists",
+			Duplicates: duplicates,
+		})
 	}
 
 	if err := h.todoRepo.Create(r.Context(), todo); err != nil {
@@ -61,7 +234,10 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.RespondJSON(w, http.StatusCreated, todo)
+	h.notifyAssignment(r, todo, nil, claims)
+
+	w.Header().Set("ETag", todoETag(todo))
+	utils.RespondJSON(w, http.StatusCreated, response)
 }
 
 // --- THIS IS THE FIXED FUNCTION ---
@@ -73,67 +249,256 @@ func (h *TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Read the new filter and sort parameters
+	opts := models.TodoListOptions{
+		Status:     r.URL.Query().Get("status"),
+		Scope:      r.URL.Query().Get("scope"),
+		Query:      strings.TrimSpace(r.URL.Query().Get("q")),
+		SearchMode: r.URL.Query().Get("search_mode"),
+		Actionable: r.URL.Query().Get("actionable") == "true",
+	}
+
+	expr, err := filter.Parse(r.URL.Query().Get("filter"), models.TodoFilterFields)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid filter", err.Error())
+		return
+	}
+	opts.Filter = expr
+
+	// sort= replaces sort_by=, which is still understood
+	if r.URL.Query().Has("sort") {
+		sort, err := parseTodoSort(r.URL.Query().Get("sort"), opts.Query != "")
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid sort", err.Error())
+			return
+		}
+		opts.Sort = sort
+	} else {
+		opts.Sort = models.LegacySortBy(r.URL.Query().Get("sort_by"))
+	}
+
 	// Get pagination parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
+	todoPaging(r, &opts)
+
+	switch opts.SearchMode {
+	case "", models.SearchModeFullText, models.SearchModeFuzzy:
+	default:
+		utils.RespondError(w, http.StatusBadRequest, "Invalid search mode", "search_mode must be one of fulltext, fuzzy")
+		return
 	}
 
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	if limit < 1 || limit > 100 {
-		limit = 10
+	if len(opts.Query) > maxSearchQueryLength {
+		utils.RespondError(w, http.StatusBadRequest, "Search query too long",
+			fmt.Sprintf("q must be at most %d characters", maxSearchQueryLength))
+		return
 	}
 
-	// Read the new filter and sort parameters
-	status := r.URL.Query().Get("status")
-	sortBy := r.URL.Query().Get("sort_by")
+	switch opts.Scope {
+	case "", models.ScopeOwn, models.ScopeShared, models.ScopeAll:
+	default:
+		utils.RespondError(w, http.StatusBadRequest, "Invalid scope", "scope must be one of own, shared, all")
+		return
+	}
+
+	switch assignee := r.URL.Query().Get("assignee"); assignee {
+	case "":
+	case "me":
+		opts.AssigneeID = &claims.UserID
+	case "unassigned":
+		opts.Unassigned = true
+	default:
+		assigneeID, err := strconv.Atoi(assignee)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid assignee", "assignee must be me, unassigned or a user ID")
+			return
+		}
+		opts.AssigneeID = &assigneeID
+	}
+
+	respondTodoList(w, r, h.todoRepo, claims.UserID, opts)
+	// --- END OF FIX ---
+}
+
+// parseTodoSort parses a todo listing's sort; rank is only allowed when searching.
+func parseTodoSort(s string, searching bool) ([]models.SortField, error) {
+	sort, err := models.ParseSort(s, models.TodoSortFields)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range sort {
+		if field.Field == "rank" && !searching {
+			return nil, errors.New("rank can only be sorted by when searching with q")
+		}
+	}
+	return sort, nil
+}
+
+// todoPaging reads the pagination parameters of todo listings: ?page=&limit=,
+// or ?cursor=&limit= (with an empty cursor for the first page) and optionally
+// include_total=true.
+func todoPaging(r *http.Request, opts *models.TodoListOptions) {
+	query := r.URL.Query()
 
-	// --- THIS LINE WAS MISSING ---
-	// It declares todos, total, and err, and uses claims, status, and sortBy
-	todos, total, err := h.todoRepo.GetByUserID(r.Context(), claims.UserID, page, limit, status, sortBy)
+	opts.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if opts.Limit < 1 || opts.Limit > 100 {
+		opts.Limit = 10
+	}
+
+	if query.Has("cursor") {
+		opts.CursorMode = true
+		opts.Cursor = query.Get("cursor")
+		opts.IncludeTotal = query.Get("include_total") == "true"
+		return
+	}
+
+	opts.Page, _ = strconv.Atoi(query.Get("page"))
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+}
+
+// respondTodoList runs a todo listing and writes one page of it. Every todo
+// listing (GET /todos, saved views) goes through here.
+func respondTodoList(w http.ResponseWriter, r *http.Request, todoRepo repository.Todo_Repository, userID int, opts models.TodoListOptions) {
+	page, err := todoRepo.GetByUserID(r.Context(), userID, opts)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid cursor", "cursors only work with the sort order they were issued for")
+			return
+		}
 		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch todos")
 		return
 	}
-	// --- END OF FIX ---
 
 	// Prepare response
 	response := models.TodoListResponse{
-		Data:  todos,
-		Page:  page,
-		Limit: limit,
-		Total: total,
+		Data:       page.Todos,
+		Page:       opts.Page,
+		Limit:      opts.Limit,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
 	}
 
 	utils.RespondJSON(w, http.StatusOK, response)
 }
 
-func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
-	// Get user from context
+// GetTodo returns one todo with its ETag. With If-None-Match naming the
+// current version it answers 304 Not Modified without a body.
+func (h *TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 	claims, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
 		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Get todo ID from URL
-	vars := mux.Vars(r)
-	todoID, err := strconv.Atoi(vars["id"])
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
 		return
 	}
 
-	// Get existing todo
-	todo, err := h.todoRepo.GetByID(r.Context(), todoID)
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
 	if err != nil {
 		utils.RespondError(w, http.StatusNotFound, "Todo not found")
 		return
 	}
 
-	// Check authorization
-	if todo.UserID != claims.UserID {
+	etag := todoETag(todo)
+	w.Header().Set("ETag", etag)
+	if header := r.Header.Get("If-None-Match"); header != "" && etagMatches(header, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+// GetSimilarTodos lists other todos whose titles resemble this one's.
+func (h *TodoHandler) GetSimilarTodos(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 20 {
+		limit = 5
+	}
+
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	similar, err := h.todoRepo.FindSimilar(r.Context(), todo, claims.UserID, limit)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch similar todos")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, similar)
+}
+
+// loadEditableTodo resolves {id} to a todo the user may edit, writing the
+// error response if there is none or If-Match names another version.
+func (h *TodoHandler) loadEditableTodo(w http.ResponseWriter, r *http.Request, userID int) (*models.Todo, bool) {
+	// Get todo ID from URL
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return nil, false
+	}
+
+	// Get existing todo (only returned if the user has access to it)
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return nil, false
+	}
+
+	// Check authorization: viewers can read but not edit
+	if !models.RoleAtLeast(todo.Role, models.RoleEditor) {
 		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+
+	if !checkIfMatch(w, r, todo) {
+		return nil, false
+	}
+
+	return todo, true
+}
+
+// todoChanges are the changes to save to a todo; nil fields stay as they are.
+// A ProjectID or AssigneeID of 0 clears it.
+type todoChanges struct {
+	Title       *string
+	Description *string
+	Completed   *bool
+	ProjectID   *int
+	AssigneeID  *int
+	StateID     *int
+}
+
+func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todo, ok := h.loadEditableTodo(w, r, claims.UserID)
+	if !ok {
 		return
 	}
 
@@ -145,19 +510,380 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update fields if provided
+	// Empty strings mean "not provided"; PATCH can clear them
+	changes := todoChanges{
+		Completed:  req.Completed,
+		ProjectID:  req.ProjectID,
+		AssigneeID: req.AssigneeID,
+		StateID:    req.StateID,
+	}
 	if req.Title != "" {
-		todo.Title = req.Title
+		changes.Title = &req.Title
 	}
 	if req.Description != "" {
-		todo.Description = req.Description
+		changes.Description = &req.Description
+	}
+
+	h.saveTodoChanges(w, r, claims, todo, changes)
+}
+
+// saveTodoChanges applies the changes to the todo with every check an update
+// needs, saves it and writes the response.
+func (h *TodoHandler) saveTodoChanges(w http.ResponseWriter, r *http.Request, claims *models.Claims, todo *models.Todo, changes todoChanges) {
+	// Update fields if provided
+	before := models.SnapshotForUndo(todo)
+	wasCompleted := todo.Completed
+	if changes.Title != nil {
+		todo.Title = *changes.Title
+	}
+	if changes.Description != nil {
+		todo.Description = *changes.Description
 	}
-	if req.Completed != nil {
-		todo.Completed = *req.Completed
+	if changes.Completed != nil {
+		todo.Completed = *changes.Completed
+	}
+	previousProject := todo.ProjectID
+	if changes.ProjectID != nil {
+		var projectID *int
+		if *changes.ProjectID != 0 {
+			projectID = changes.ProjectID
+		}
+		canFile := func(projectID int) bool { return h.canFileInProject(r, projectID, claims.UserID) }
+		if msg := projectMoveError(todo, projectID, canFile); msg != "" {
+			utils.RespondError(w, http.StatusForbidden, msg)
+			return
+		}
+		todo.ProjectID = projectID
+	}
+	if err := h.placeInWorkflow(r, todo, changes.StateID, claims.UserID); err != nil {
+		if errors.Is(err, errInvalidState) {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid state", err.Error())
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to update todo")
+		return
+	}
+
+	// The assignee is checked against the todo as it will be saved, so moving a todo
+	// into a project and assigning it to a project member works in one request
+	previousAssignee := todo.AssigneeID
+	if changes.AssigneeID != nil {
+		if *changes.AssigneeID == 0 {
+			todo.AssigneeID = nil
+		} else {
+			allowed, err := h.todoRepo.CanBeAssigned(r.Context(), todo, *changes.AssigneeID, claims.UserID)
+			if err != nil {
+				utils.RespondError(w, http.StatusInternalServerError, "Failed to update todo")
+				return
+			}
+			if !allowed {
+				utils.RespondError(w, http.StatusBadRequest, "Assignee does not have access to this todo")
+				return
+			}
+			todo.AssigneeID = changes.AssigneeID
+		}
+	} else if todo.AssigneeID != nil && !sameID(previousProject, todo.ProjectID) {
+		// The assignee was given access through the old project, which the
+		// move may have taken away; the client must pick another or unassign
+		allowed, err := h.todoRepo.CanBeAssigned(r.Context(), todo, *todo.AssigneeID, claims.UserID)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to update todo")
+			return
+		}
+		if !allowed {
+			utils.RespondError(w, http.StatusBadRequest, "Assignee does not have access to this todo",
+				"the assignee cannot see the todo in its new project; assign it to someone else or set assignee_id to 0")
+			return
+		}
+	}
+
+	// Completing a todo that is still blocked is up to the user's policy
+	response := models.UpdateTodoResponse{Todo: todo}
+	if todo.Completed && !wasCompleted && todo.Blocked {
+		settings, err := h.settingsRepo.Get(r.Context(), claims.UserID)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to update todo")
+			return
+		}
+		if settings.BlockedCompletion == models.BlockedCompletionReject {
+			utils.RespondError(w, http.StatusConflict, "Todo is blocked",
+				fmt.Sprintf("it is blocked by open todos %v", todo.BlockedBy))
+			return
+		}
+		response.Warnings = append(response.Warnings, models.TodoWarning{
+			Code:      models.WarningBlocked,
+			Message:   "The todo was completed while it is still blocked by open todos",
+			BlockedBy: todo.BlockedBy,
+		})
 	}
 
-	// Update in database
-	if err := h.todoRepo.Update(r.Context(), todo); err != nil {
+	// Update in database; it fails if the todo changed since it was read
+	if err := h.todoRepo.Update(r.Context(), todo, claims.UserID); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.respondVersionConflict(w, r, todo.ID, claims.UserID)
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to update todo")
+		return
+	}
+
+	h.notifyAssignment(r, todo, previousAssignee, claims)
+
+	before.Version = todo.Version
+	response.Undo = issueUndo(r, h.undoRepo, claims.UserID, models.UndoActionUpdate, []models.UndoSnapshot{before})
+
+	w.Header().Set("ETag", todoETag(todo))
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+// MoveTodo places a todo right before or right after another one in the manual order.
+func (h *TodoHandler) MoveTodo(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	var req models.MoveTodoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if (req.Before == nil) == (req.After == nil) {
+		utils.RespondError(w, http.StatusBadRequest, "Give either before or after")
+		return
+	}
+	anchorID := req.Before
+	if req.After != nil {
+		anchorID = req.After
+	}
+	if *anchorID == todoID {
+		utils.RespondError(w, http.StatusBadRequest, "A todo cannot be moved next to itself")
+		return
+	}
+
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	if !models.RoleAtLeast(todo.Role, models.RoleEditor) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if err := h.todoRepo.Move(r.Context(), todo, *anchorID, req.After != nil, claims.UserID); err != nil {
+		if errors.Is(err, repository.ErrAnchorNotFound) {
+			utils.RespondError(w, http.StatusBadRequest, "Anchor todo not found")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to move todo")
+		return
+	}
+
+	w.Header().Set("ETag", todoETag(todo))
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+// GetDependencies lists the todos this one is blocked by and the ones it blocks.
+func (h *TodoHandler) GetDependencies(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	if _, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	deps, err := h.todoRepo.GetDependencies(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch dependencies")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, deps)
+}
+
+// AddDependency marks the todo as blocked by another one.
+func (h *TodoHandler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	var req models.DependencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "blocker_id is required")
+		return
+	}
+
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	if !models.RoleAtLeast(todo.Role, models.RoleEditor) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if err := h.todoRepo.AddDependency(r.Context(), todoID, req.BlockerID, claims.UserID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrBlockerNotFound):
+			utils.RespondError(w, http.StatusBadRequest, "Blocking todo not found")
+		case errors.Is(err, repository.ErrDependencyCycle):
+			utils.RespondError(w, http.StatusConflict, "Dependency would create a cycle",
+				fmt.Sprintf("todo %d already depends on todo %d, directly or indirectly", req.BlockerID, todoID))
+		case errors.Is(err, repository.ErrDuplicateDependency):
+			utils.RespondError(w, http.StatusConflict, "Dependency already exists")
+		default:
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to add dependency")
+		}
+		return
+	}
+
+	deps, err := h.todoRepo.GetDependencies(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch dependencies")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, deps)
+}
+
+// RemoveDependency unblocks the todo from one of its blockers.
+func (h *TodoHandler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	todoID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+	blockerID, err := strconv.Atoi(vars["blockerId"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid blocking todo ID")
+		return
+	}
+
+	if err := h.todoRepo.RemoveDependency(r.Context(), todoID, blockerID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Dependency not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseAge parses an age such as 12h, 30d or 2w.
+func parseAge(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(s) < 2 {
+		return 0, errors.New("expected a number followed by h, d or w")
+	}
+	unit, ok := units[s[len(s)-1]]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if !ok || err != nil || n < 0 {
+		return 0, errors.New("expected a number followed by h, d or w")
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// ArchiveCompleted archives the user's completed todos, or with ?older_than=
+// (e.g. 30d) only those that haven't changed for that long.
+func (h *TodoHandler) ArchiveCompleted(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var age time.Duration
+	if param := r.URL.Query().Get("older_than"); param != "" {
+		var err error
+		if age, err = parseAge(param); err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid older_than", err.Error())
+			return
+		}
+	}
+
+	archived, err := h.todoRepo.ArchiveCompleted(r.Context(), claims.UserID, time.Now().Add(-age))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to archive todos")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.ArchiveResponse{Archived: archived})
+}
+
+// ArchiveTodo and UnarchiveTodo move a single todo in and out of the archive.
+func (h *TodoHandler) ArchiveTodo(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+func (h *TodoHandler) UnarchiveTodo(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *TodoHandler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	if !models.RoleAtLeast(todo.Role, models.RoleEditor) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if err := h.todoRepo.SetArchived(r.Context(), todo, archived, claims.UserID); err != nil {
 		utils.RespondError(w, http.StatusInternalServerError, "Failed to update todo")
 		return
 	}
@@ -165,6 +891,8 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, http.StatusOK, todo)
 }
 
+// DeleteTodo moves the todo to the trash. The response has no body; the token
+// that undoes the delete comes in the Undo-Token and Undo-Expires headers.
 func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	claims, ok := middleware.GetUserFromContext(r.Context())
@@ -181,11 +909,32 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	if !checkIfMatch(w, r, todo) {
+		return
+	}
+
 	// Delete todo
-	if err := h.todoRepo.Delete(r.Context(), todoID, claims.UserID); err != nil {
+	if err := h.todoRepo.Delete(r.Context(), todo, claims.UserID); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.respondVersionConflict(w, r, todo.ID, claims.UserID)
+			return
+		}
 		utils.RespondError(w, http.StatusNotFound, "Todo not found")
 		return
 	}
 
+	// Only the version changed; it is now the one the trash left the todo at
+	snapshot := models.SnapshotForUndo(todo)
+	snapshot.Version = todo.Version
+	if undo := issueUndo(r, h.undoRepo, claims.UserID, models.UndoActionDelete, []models.UndoSnapshot{snapshot}); undo != nil {
+		w.Header().Set(models.UndoTokenHeader, undo.Token)
+		w.Header().Set(models.UndoExpiresHeader, undo.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
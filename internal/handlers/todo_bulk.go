@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+// bulkPlan checks the items of a bulk request and turns the ones that pass
+// into writes. The todos the request names are loaded in one query up front;
+// projects, their states and the user's settings are looked up once each.
+// Items see the changes of the items before them, so an update after a move
+// works on the moved todo.
+type bulkPlan struct {
+	h      *TodoHandler
+	r      *http.Request
+	claims *models.Claims
+
+	original map[int]*models.Todo // as loaded, for undo
+	current  map[int]*models.Todo // as the items so far left them; nil once deleted
+	canFile  map[int]bool
+	states   map[int][]models.WorkflowState
+	settings *models.UserSettings
+
+	results []models.BulkItemResult
+	writes  []models.BulkWrite
+	// per write: the result it fills in and the assignee before it
+	writeResults []int
+	previous     []*int
+}
+
+// errBulkLookup aborts planning when looking something up fails; the request
+// then fails as a whole.
+var errBulkLookup = errors.New("bulk lookup failed")
+
+func (p *bulkPlan) fail(index int, op string, id *int, status int, message string) {
+	p.results = append(p.results, models.BulkItemResult{Operation: index, Op: op, ID: id, Status: status, Error: message})
+}
+
+func (p *bulkPlan) write(index int, op string, kind string, todo *models.Todo, previous *int, status int, warnings []models.TodoWarning) {
+	result := models.BulkItemResult{Operation: index, Op: op, Status: status, Todo: todo, Warnings: warnings}
+	if kind != models.BulkWriteInsert {
+		result.ID = &todo.ID
+	}
+	if kind == models.BulkWriteTrash {
+		result.Todo = nil
+		p.current[todo.ID] = nil
+	} else if kind == models.BulkWriteUpdate {
+		p.current[todo.ID] = todo
+	}
+
+	p.writeResults = append(p.writeResults, len(p.results))
+	p.results = append(p.results, result)
+	p.writes = append(p.writes, models.BulkWrite{Op: kind, Todo: todo})
+	p.previous = append(p.previous, previous)
+}
+
+// todo returns a copy of the todo to change, or writes the item's failure if
+// the user can't see it or their role is below minRole.
+func (p *bulkPlan) todo(index int, op string, id int, minRole string) (*models.Todo, bool) {
+	current := p.current[id]
+	if current == nil {
+		p.fail(index, op, &id, http.StatusNotFound, "Todo not found")
+		return nil, false
+	}
+	if !models.RoleAtLeast(current.Role, minRole) {
+		p.fail(index, op, &id, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+
+	todo := *current
+	return &todo, true
+}
+
+func (p *bulkPlan) canFileInProject(projectID int) bool {
+	allowed, ok := p.canFile[projectID]
+	if !ok {
+		allowed = p.h.canFileInProject(p.r, projectID, p.claims.UserID)
+		p.canFile[projectID] = allowed
+	}
+	return allowed
+}
+
+// moveToProject moves the todo to projectID (nil takes it out of its project),
+// writing the item's failure if the user may not; see projectMoveError.
+func (p *bulkPlan) moveToProject(index int, op string, todo *models.Todo, projectID *int) bool {
+	if msg := projectMoveError(todo, projectID, p.canFileInProject); msg != "" {
+		p.fail(index, op, &todo.ID, http.StatusForbidden, msg)
+		return false
+	}
+	todo.ProjectID = projectID
+	return true
+}
+
+func (p *bulkPlan) place(todo *models.Todo, stateID *int) error {
+	var states []models.WorkflowState
+	if todo.ProjectID != nil {
+		var ok bool
+		if states, ok = p.states[*todo.ProjectID]; !ok {
+			var err error
+			if states, err = p.h.workflowRepo.ListByProject(p.r.Context(), *todo.ProjectID, p.claims.UserID); err != nil {
+				return errBulkLookup
+			}
+			p.states[*todo.ProjectID] = states
+		}
+	}
+	return placeInStates(todo, states, stateID)
+}
+
+// checkCompletion applies the user's blocked completion policy to a todo the
+// item completes. It returns the warnings for the item, or false if the item
+// was refused.
+func (p *bulkPlan) checkCompletion(index int, op string, todo *models.Todo, wasCompleted bool) ([]models.TodoWarning, bool, error) {
+	if !todo.Completed || wasCompleted || !todo.Blocked {
+		return nil, true, nil
+	}
+
+	if p.settings == nil {
+		settings, err := p.h.settingsRepo.Get(p.r.Context(), p.claims.UserID)
+		if err != nil {
+			return nil, false, errBulkLookup
+		}
+		p.settings = settings
+	}
+
+	if p.settings.BlockedCompletion == models.BlockedCompletionReject {
+		p.fail(index, op, &todo.ID, http.StatusConflict, fmt.Sprintf("Todo is blocked by open todos %v", todo.BlockedBy))
+		return nil, false, nil
+	}
+	return []models.TodoWarning{{
+		Code:      models.WarningBlocked,
+		Message:   "The todo was completed while it is still blocked by open todos",
+		BlockedBy: todo.BlockedBy,
+	}}, true, nil
+}
+
+// assign checks the assignee against the todo as it will be saved, as
+// UpdateTodo does; 0 unassigns.
+func (p *bulkPlan) assign(index int, op string, todo *models.Todo, assigneeID *int) (bool, error) {
+	if assigneeID == nil {
+		return true, nil
+	}
+	if *assigneeID == 0 {
+		todo.AssigneeID = nil
+		return true, nil
+	}
+
+	allowed, err := p.h.todoRepo.CanBeAssigned(p.r.Context(), todo, *assigneeID, p.claims.UserID)
+	if err != nil {
+		return false, errBulkLookup
+	}
+	if !allowed {
+		p.fail(index, op, idOf(todo), http.StatusBadRequest, "Assignee does not have access to this todo")
+		return false, nil
+	}
+	todo.AssigneeID = assigneeID
+	return true, nil
+}
+
+// idOf is the todo's ID for its item result, nil for a todo not created yet.
+func idOf(todo *models.Todo) *int {
+	if todo.ID == 0 {
+		return nil
+	}
+	return &todo.ID
+}
+
+// placeItem puts the todo in its workflow, writing the item's failure if the
+// state asked for is invalid.
+func (p *bulkPlan) placeItem(index int, op string, todo *models.Todo, stateID *int) (bool, error) {
+	if err := p.place(todo, stateID); err != nil {
+		if errors.Is(err, errInvalidState) {
+			p.fail(index, op, idOf(todo), http.StatusBadRequest, "Invalid state: "+err.Error())
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *bulkPlan) create(index int, req *models.CreateTodoRequest) error {
+	op := models.BulkCreate
+	if req == nil {
+		p.fail(index, op, nil, http.StatusBadRequest, "todo is required")
+		return nil
+	}
+
+	if req.ProjectID != nil && !p.canFileInProject(*req.ProjectID) {
+		p.fail(index, op, nil, http.StatusForbidden, "You cannot add todos to this project")
+		return nil
+	}
+
+	todo := &models.Todo{
+		UserID:      p.claims.UserID,
+		ProjectID:   req.ProjectID,
+		Title:       req.Title,
+		Description: req.Description,
+		Role:        models.RoleOwner,
+	}
+	if ok, err := p.placeItem(index, op, todo, req.StateID); !ok {
+		return err
+	}
+	if req.AssigneeID != nil && *req.AssigneeID != 0 {
+		if ok, err := p.assign(index, op, todo, req.AssigneeID); !ok {
+			return err
+		}
+	}
+
+	p.write(index, op, models.BulkWriteInsert, todo, nil, http.StatusCreated, nil)
+	return nil
+}
+
+func (p *bulkPlan) update(index int, id int, req *models.UpdateTodoRequest) error {
+	op := models.BulkUpdate
+	todo, ok := p.todo(index, op, id, models.RoleEditor)
+	if !ok {
+		return nil
+	}
+
+	wasCompleted, previous := todo.Completed, todo.AssigneeID
+	if req.Title != "" {
+		todo.Title = req.Title
+	}
+	if req.Description != "" {
+		todo.Description = req.Description
+	}
+	if req.Completed != nil {
+		todo.Completed = *req.Completed
+	}
+	if req.ProjectID != nil {
+		var projectID *int
+		if *req.ProjectID != 0 {
+			projectID = req.ProjectID
+		}
+		if !p.moveToProject(index, op, todo, projectID) {
+			return nil
+		}
+	}
+	if ok, err := p.placeItem(index, op, todo, req.StateID); !ok {
+		return err
+	}
+	if ok, err := p.assign(index, op, todo, req.AssigneeID); !ok {
+		return err
+	}
+
+	warnings, ok, err := p.checkCompletion(index, op, todo, wasCompleted)
+	if !ok {
+		return err
+	}
+
+	p.write(index, op, models.BulkWriteUpdate, todo, previous, http.StatusOK, warnings)
+	return nil
+}
+
+func (p *bulkPlan) complete(index int, id int, completed bool) error {
+	op := models.BulkComplete
+	todo, ok := p.todo(index, op, id, models.RoleEditor)
+	if !ok {
+		return nil
+	}
+
+	wasCompleted := todo.Completed
+	todo.Completed = completed
+	if ok, err := p.placeItem(index, op, todo, nil); !ok {
+		return err
+	}
+
+	warnings, ok, err := p.checkCompletion(index, op, todo, wasCompleted)
+	if !ok {
+		return err
+	}
+
+	p.write(index, op, models.BulkWriteUpdate, todo, todo.AssigneeID, http.StatusOK, warnings)
+	return nil
+}
+
+func (p *bulkPlan) move(index int, id int, projectID int) error {
+	op := models.BulkMove
+	todo, ok := p.todo(index, op, id, models.RoleEditor)
+	if !ok {
+		return nil
+	}
+
+	var target *int
+	if projectID != 0 {
+		target = &projectID
+	}
+	if !p.moveToProject(index, op, todo, target) {
+		return nil
+	}
+	if ok, err := p.placeItem(index, op, todo, nil); !ok {
+		return err
+	}
+
+	p.write(index, op, models.BulkWriteUpdate, todo, todo.AssigneeID, http.StatusOK, nil)
+	return nil
+}
+
+func (p *bulkPlan) delete(index int, id int) {
+	todo, ok := p.todo(index, models.BulkDelete, id, models.RoleOwner)
+	if !ok {
+		return
+	}
+	p.write(index, models.BulkDelete, models.BulkWriteTrash, todo, nil, http.StatusOK, nil)
+}
+
+// operation plans every item of one operation.
+func (p *bulkPlan) operation(index int, op models.BulkOperation) error {
+	if op.Op == models.BulkCreate {
+		return p.create(index, op.Todo)
+	}
+
+	if len(op.IDs) == 0 {
+		p.fail(index, op.Op, nil, http.StatusBadRequest, "ids is required")
+		return nil
+	}
+
+	for _, id := range op.IDs {
+		var err error
+		switch op.Op {
+		case models.BulkUpdate:
+			if op.Changes == nil {
+				p.fail(index, op.Op, &id, http.StatusBadRequest, "changes is required")
+				continue
+			}
+			err = p.update(index, id, op.Changes)
+		case models.BulkComplete:
+			completed := op.Completed == nil || *op.Completed
+			err = p.complete(index, id, completed)
+		case models.BulkMove:
+			if op.ProjectID == nil {
+				p.fail(index, op.Op, &id, http.StatusBadRequest, "project_id is required")
+				continue
+			}
+			err = p.move(index, id, *op.ProjectID)
+		case models.BulkDelete:
+			p.delete(index, id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undoSnapshots are what undoing the applied writes takes: each changed todo
+// as it was loaded, once, and each created todo, at the version their last
+// write left them at.
+func (p *bulkPlan) undoSnapshots(errs []error) []models.UndoSnapshot {
+	versions := map[int]int{}
+	for i, write := range p.writes {
+		if errs[i] == nil {
+			versions[write.Todo.ID] = write.Todo.Version
+		}
+	}
+
+	snapshots := []models.UndoSnapshot{}
+	seen := map[int]bool{}
+	for i, write := range p.writes {
+		if errs[i] != nil {
+			continue
+		}
+		if write.Op == models.BulkWriteInsert {
+			snapshots = append(snapshots, models.UndoSnapshot{TodoID: write.Todo.ID, Version: versions[write.Todo.ID], Created: true})
+			continue
+		}
+		if !seen[write.Todo.ID] {
+			seen[write.Todo.ID] = true
+			snapshot := models.SnapshotForUndo(p.original[write.Todo.ID])
+			snapshot.Version = versions[write.Todo.ID]
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots
+}
+
+// BulkTodos applies a list of operations on todos in one transaction and
+// reports the outcome of every item. In atomic mode (the default) nothing is
+// applied unless every item can be, and the response is 422 otherwise; in
+// best_effort mode the items that fail are skipped.
+func (h *TodoHandler) BulkTodos(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.BulkTodoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid bulk request", err.Error())
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.BulkAtomic
+	}
+
+	items := 0
+	ids := []int{}
+	for _, op := range req.Operations {
+		if op.Op == models.BulkCreate {
+			items++
+			continue
+		}
+		items += len(op.IDs)
+		ids = append(ids, op.IDs...)
+	}
+	if items > models.MaxBulkItems {
+		utils.RespondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d items per request", models.MaxBulkItems))
+		return
+	}
+
+	// One query checks the user's access to every todo the request names
+	todos, err := h.todoRepo.GetByIDs(r.Context(), ids, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to apply bulk changes")
+		return
+	}
+
+	plan := &bulkPlan{
+		h:        h,
+		r:        r,
+		claims:   claims,
+		original: todos,
+		current:  make(map[int]*models.Todo, len(todos)),
+		canFile:  map[int]bool{},
+		states:   map[int][]models.WorkflowState{},
+		results:  []models.BulkItemResult{},
+	}
+	for id, todo := range todos {
+		plan.current[id] = todo
+	}
+
+	for i, op := range req.Operations {
+		if err := plan.operation(i, op); err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to apply bulk changes")
+			return
+		}
+	}
+
+	response := models.BulkTodoResponse{Mode: req.Mode, Results: plan.results}
+	atomic := req.Mode == models.BulkAtomic
+	refused := len(plan.writes) < len(plan.results)
+
+	errs := make([]error, len(plan.writes))
+	applied := !(atomic && refused)
+	if applied && len(plan.writes) > 0 {
+		errs, err = h.todoRepo.ApplyBulk(r.Context(), claims.UserID, plan.writes, atomic)
+		if err != nil && !errors.Is(err, repository.ErrBulkAborted) {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to apply bulk changes")
+			return
+		}
+		applied = err == nil
+	}
+
+	for i, write := range plan.writes {
+		result := &response.Results[plan.writeResults[i]]
+		switch {
+		case errors.Is(errs[i], repository.ErrVersionConflict):
+			result.Status, result.Error, result.Todo = http.StatusPreconditionFailed, "The todo was changed by someone else", nil
+		case errs[i] != nil:
+			result.Status, result.Error, result.Todo = http.StatusInternalServerError, "Failed to save todo", nil
+		case !applied:
+			result.Status, result.Error, result.Todo = http.StatusFailedDependency, "Not applied: another item failed", nil
+		case write.Op != models.BulkWriteTrash:
+			h.notifyAssignment(r, write.Todo, plan.previous[i], claims)
+		}
+	}
+
+	for _, result := range response.Results {
+		if result.Status < http.StatusMultipleChoices {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+
+	if !applied {
+		utils.RespondJSON(w, http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	response.Undo = issueUndo(r, h.undoRepo, claims.UserID, models.UndoActionBulk, plan.undoSnapshots(errs))
+	utils.RespondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+// historyPaging reads ?page=&limit= for history listings.
+func historyPaging(r *http.Request) (page, limit int) {
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	return page, limit
+}
+
+// GetTodoHistory lists the todo's revisions, newest first.
+func (h *TodoHandler) GetTodoHistory(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	if _, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	page, limit := historyPaging(r)
+	revisions, total, err := h.todoRepo.ListRevisions(r.Context(), todoID, claims.UserID, page, limit)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch history")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.RevisionListResponse{
+		Data:  revisions,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// GetActivity lists the changes to all todos the user can see in the workspace, newest first.
+func (h *TodoHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	page, limit := historyPaging(r)
+	activity, total, err := h.todoRepo.ListActivity(r.Context(), claims.UserID, page, limit)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch activity")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.RevisionListResponse{
+		Data:  activity,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// RevertTodo puts the todo's fields back the way they were after the given
+// revision. The revert is itself saved as a new revision. It is refused if the
+// todo can't go back into its old project or to its old assignee.
+func (h *TodoHandler) RevertTodo(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	var req models.RevertTodoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "revision is required")
+		return
+	}
+
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	if !models.RoleAtLeast(todo.Role, models.RoleEditor) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if !checkIfMatch(w, r, todo) {
+		return
+	}
+
+	rev, err := h.todoRepo.GetRevision(r.Context(), todoID, req.Revision, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Revision not found")
+		return
+	}
+
+	var snap models.TodoSnapshot
+	if err := json.Unmarshal(rev.Snapshot, &snap); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to read revision")
+		return
+	}
+
+	todo.Title = snap.Title
+	todo.Description = ""
+	if snap.Description != nil {
+		todo.Description = *snap.Description
+	}
+	todo.Completed = snap.Completed
+
+	canFile := func(projectID int) bool { return h.canFileInProject(r, projectID, claims.UserID) }
+	if msg := projectMoveError(todo, snap.ProjectID, canFile); msg != "" {
+		utils.RespondError(w, http.StatusForbidden, "Cannot revert", msg)
+		return
+	}
+	todo.ProjectID = snap.ProjectID
+
+	// The old state is kept if it still exists and agrees with the old completion
+	todo.StateID = snap.StateID
+	if err := h.placeInWorkflow(r, todo, nil, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to revert todo")
+		return
+	}
+
+	previousAssignee := todo.AssigneeID
+	if snap.AssigneeID != nil && (todo.AssigneeID == nil || *todo.AssigneeID != *snap.AssigneeID) {
+		allowed, err := h.todoRepo.CanBeAssigned(r.Context(), todo, *snap.AssigneeID, claims.UserID)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to revert todo")
+			return
+		}
+		if !allowed {
+			utils.RespondError(w, http.StatusConflict, "Cannot revert", "the old assignee no longer has access to this todo")
+			return
+		}
+	}
+	todo.AssigneeID = snap.AssigneeID
+
+	if err := h.todoRepo.Update(r.Context(), todo, claims.UserID); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			h.respondVersionConflict(w, r, todo.ID, claims.UserID)
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to revert todo")
+		return
+	}
+
+	h.notifyAssignment(r, todo, previousAssignee, claims)
+
+	w.Header().Set("ETag", todoETag(todo))
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
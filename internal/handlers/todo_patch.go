@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/patch"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+// maxPatchBytes caps the body of a PATCH.
+const maxPatchBytes = 64 << 10
+
+// sameID reports whether two optional IDs are the same.
+func sameID(a, b *int) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// orZero turns a cleared ID into the 0 that todoChanges clears with.
+func orZero(id *int) *int {
+	if id == nil {
+		zero := 0
+		return &zero
+	}
+	return id
+}
+
+// PatchTodo changes a todo with a JSON Merge Patch (application/merge-patch+json)
+// or a JSON Patch (application/json-patch+json), both applied to the todo's
+// models.TodoPatchDocument. The patch is applied in full and the result
+// validated before anything is saved, so a failing operation or test changes
+// nothing. The result is then saved with the same checks as PUT.
+func (h *TodoHandler) PatchTodo(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != patch.MergePatchType && mediaType != patch.JSONPatchType) {
+		w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+		utils.RespondError(w, http.StatusUnsupportedMediaType, "Unsupported patch format",
+			"send "+patch.MergePatchType+" or "+patch.JSONPatchType)
+		return
+	}
+
+	todo, ok := h.loadEditableTodo(w, r, claims.UserID)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	doc, err := json.Marshal(models.PatchDocumentFor(todo))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to update todo")
+		return
+	}
+
+	var patched []byte
+	if mediaType == patch.MergePatchType {
+		patched, err = patch.Merge(doc, body)
+	} else {
+		patched, err = patch.Apply(doc, body)
+	}
+	if err != nil {
+		if errors.Is(err, patch.ErrTestFailed) {
+			utils.RespondError(w, http.StatusConflict, "Patch test failed", err.Error())
+			return
+		}
+		utils.RespondError(w, http.StatusBadRequest, "Invalid patch", err.Error())
+		return
+	}
+
+	var result models.TodoPatchDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		utils.RespondError(w, http.StatusUnprocessableEntity, "Invalid todo", err.Error())
+		return
+	}
+	if err := h.validator.Struct(result); err != nil {
+		utils.RespondError(w, http.StatusUnprocessableEntity, "Invalid todo", "title is required and completed must be true or false")
+		return
+	}
+
+	// Only what the patch changed is checked again, so an editor can patch a
+	// todo in a project they can't add todos to
+	changes := todoChanges{
+		Title:       &result.Title,
+		Description: &result.Description,
+		Completed:   result.Completed,
+	}
+	if !sameID(result.ProjectID, todo.ProjectID) {
+		changes.ProjectID = orZero(result.ProjectID)
+	}
+	if !sameID(result.AssigneeID, todo.AssigneeID) {
+		changes.AssigneeID = orZero(result.AssigneeID)
+	}
+	if result.StateID != nil && !sameID(result.StateID, todo.StateID) {
+		changes.StateID = result.StateID
+	}
+
+	h.saveTodoChanges(w, r, claims, todo, changes)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+func TestProjectMoveError(t *testing.T) {
+	current, owned, closed := 1, 2, 3
+	canFile := func(projectID int) bool { return projectID != closed }
+
+	tests := []struct {
+		name      string
+		role      string
+		projectID *int
+		allowed   bool
+	}{
+		{"editor keeps the project", models.RoleEditor, &current, true},
+		{"editor moves it into their own project", models.RoleEditor, &owned, false},
+		{"editor takes it out of its project", models.RoleEditor, nil, false},
+		{"owner moves it", models.RoleOwner, &owned, true},
+		{"owner takes it out of its project", models.RoleOwner, nil, true},
+		{"owner moves it into a project they can't add to", models.RoleOwner, &closed, false},
+	}
+
+	for _, tt := range tests {
+		todo := &models.Todo{ProjectID: &current, Role: tt.role}
+		if got := projectMoveError(todo, tt.projectID, canFile) == ""; got != tt.allowed {
+			t.Errorf("%s: allowed = %v, want %v", tt.name, got, tt.allowed)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type TrashHandler struct {
+	todoRepo repository.Todo_Repository
+	// retentionDays is how long todos stay in the trash before the purge job removes them
+	retentionDays int
+}
+
+func NewTrashHandler(todoRepo repository.Todo_Repository, retentionDays int) *TrashHandler {
+	return &TrashHandler{
+		todoRepo:      todoRepo,
+		retentionDays: retentionDays,
+	}
+}
+
+// GetTrash lists the todos the user deleted, most recently deleted first.
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	todos, total, err := h.todoRepo.ListTrash(r.Context(), claims.UserID, page, limit)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch trash")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.TrashListResponse{
+		Data:          todos,
+		Page:          page,
+		Limit:         limit,
+		Total:         total,
+		RetentionDays: h.retentionDays,
+	})
+}
+
+// RestoreTodo takes a todo out of the trash.
+func (h *TrashHandler) RestoreTodo(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	if err := h.todoRepo.Restore(r.Context(), todoID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found in trash")
+		return
+	}
+
+	todo, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch restored todo")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, todo)
+}
+
+// EmptyTrash permanently deletes everything in the user's trash.
+func (h *TrashHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	purged, err := h.todoRepo.EmptyTrash(r.Context(), claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to empty trash")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type UndoHandler struct {
+	undoRepo repository.Undo_Repository
+}
+
+func NewUndoHandler(undoRepo repository.Undo_Repository) *UndoHandler {
+	return &UndoHandler{undoRepo: undoRepo}
+}
+
+// issueUndo records the prior state of the todos a change touched and returns
+// the token that undoes it. The change has already been made, so failing to
+// record it is only logged and the response goes out without a token.
+func issueUndo(r *http.Request, undoRepo repository.Undo_Repository, userID int, action string, todos []models.UndoSnapshot) *models.UndoInfo {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		log.Printf("Error generating undo token: %v", err)
+		return nil
+	}
+
+	undo := &models.Undo{
+		TokenHash: utils.HashToken(token),
+		UserID:    userID,
+		Action:    action,
+		Todos:     todos,
+		ExpiresAt: time.Now().Add(models.UndoWindow),
+	}
+	if err := undoRepo.Create(r.Context(), undo); err != nil {
+		log.Printf("Error saving undo token for user %d: %v", userID, err)
+		return nil
+	}
+
+	return &models.UndoInfo{Token: token, ExpiresAt: undo.ExpiresAt}
+}
+
+// Undo puts the todos of an earlier change back the way they were. Tokens
+// work once, for the user who made the change, within models.UndoWindow.
+func (h *UndoHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	restored, skipped, err := h.undoRepo.Redeem(r.Context(), utils.HashToken(mux.Vars(r)["token"]), claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUndoNotFound) {
+			utils.RespondError(w, http.StatusNotFound, "Undo token not found, expired or already used")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to undo")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.UndoResponse{Restored: restored, Skipped: skipped})
+}
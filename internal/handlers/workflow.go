@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type WorkflowHandler struct {
+	workflowRepo repository.WorkflowState_Repository
+	projectRepo  repository.Project_Repository
+	todoRepo     repository.Todo_Repository
+	validator    *validator.Validate
+}
+
+func NewWorkflowHandler(workflowRepo repository.WorkflowState_Repository, projectRepo repository.Project_Repository, todoRepo repository.Todo_Repository) *WorkflowHandler {
+	return &WorkflowHandler{
+		workflowRepo: workflowRepo,
+		projectRepo:  projectRepo,
+		todoRepo:     todoRepo,
+		validator:    validator.New(),
+	}
+}
+
+// loadProject resolves {id} to a project the user can see, writing the error
+// response if there is none or the user's role is below minRole.
+func (h *WorkflowHandler) loadProject(w http.ResponseWriter, r *http.Request, userID int, minRole string) (*models.Project, bool) {
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid project ID")
+		return nil, false
+	}
+
+	project, err := h.projectRepo.GetByID(r.Context(), projectID, userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Project not found")
+		return nil, false
+	}
+
+	if !models.RoleAtLeast(project.Role, minRole) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+
+	return project, true
+}
+
+// loadState resolves {stateId} to one of the project's states.
+func (h *WorkflowHandler) loadState(w http.ResponseWriter, r *http.Request, project *models.Project, userID int) (*models.WorkflowState, bool) {
+	stateID, err := strconv.Atoi(mux.Vars(r)["stateId"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid state ID")
+		return nil, false
+	}
+
+	state, err := h.workflowRepo.GetByID(r.Context(), stateID, userID)
+	if err != nil || state.ProjectID != project.ID {
+		utils.RespondError(w, http.StatusNotFound, "State not found")
+		return nil, false
+	}
+
+	return state, true
+}
+
+func (h *WorkflowHandler) GetStates(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	project, ok := h.loadProject(w, r, claims.UserID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	states, err := h.workflowRepo.ListByProject(r.Context(), project.ID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch states")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, states)
+}
+
+func (h *WorkflowHandler) decodeStateRequest(w http.ResponseWriter, r *http.Request) (*models.WorkflowStateRequest, bool) {
+	var req models.WorkflowStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Name is required and at most 50 characters; position starts at 1")
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// respondStateError writes the response for a failed state change.
+func respondStateError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateStateName):
+		utils.RespondError(w, http.StatusConflict, "A state with this name already exists")
+	case errors.Is(err, repository.ErrLastState):
+		utils.RespondError(w, http.StatusConflict, "A project needs at least one open and one done state")
+	case errors.Is(err, repository.ErrStateNotEmpty):
+		utils.RespondError(w, http.StatusConflict, "The state still has todos", "give move_to to move them to another state")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// CreateState adds a state to the project's workflow. Editors manage the
+// workflow, like they can rename the project.
+func (h *WorkflowHandler) CreateState(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	project, ok := h.loadProject(w, r, claims.UserID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodeStateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	state := &models.WorkflowState{
+		ProjectID: project.ID,
+		Name:      req.Name,
+		IsDone:    req.IsDone,
+	}
+	if req.Position != nil {
+		state.Position = *req.Position
+	}
+
+	if err := h.workflowRepo.Create(r.Context(), state, claims.UserID); err != nil {
+		respondStateError(w, err, "Failed to create state")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, state)
+}
+
+// UpdateState renames or moves a state, or changes whether it counts as done;
+// its todos are completed or reopened to match.
+func (h *WorkflowHandler) UpdateState(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	project, ok := h.loadProject(w, r, claims.UserID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	state, ok := h.loadState(w, r, project, claims.UserID)
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodeStateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	state.Name = req.Name
+	state.IsDone = req.IsDone
+	state.Position = 0
+	if req.Position != nil {
+		state.Position = *req.Position
+	}
+
+	if err := h.workflowRepo.Update(r.Context(), state, claims.UserID); err != nil {
+		respondStateError(w, err, "Failed to update state")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, state)
+}
+
+// DeleteState removes a state; ?move_to= names the state its todos go to.
+func (h *WorkflowHandler) DeleteState(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	project, ok := h.loadProject(w, r, claims.UserID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	state, ok := h.loadState(w, r, project, claims.UserID)
+	if !ok {
+		return
+	}
+
+	var moveTo *int
+	if param := r.URL.Query().Get("move_to"); param != "" {
+		targetID, err := strconv.Atoi(param)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid move_to")
+			return
+		}
+		target, err := h.workflowRepo.GetByID(r.Context(), targetID, claims.UserID)
+		if err != nil || target.ProjectID != project.ID || target.ID == state.ID {
+			utils.RespondError(w, http.StatusBadRequest, "move_to must be another state of the project")
+			return
+		}
+		moveTo = &target.ID
+	}
+
+	if err := h.workflowRepo.Delete(r.Context(), state, moveTo, claims.UserID); err != nil {
+		respondStateError(w, err, "Failed to delete state")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// boardColumnOptions lists one column of a board: the todos in the state that
+// the user can see, in manual order, paged by cursor.
+func boardColumnOptions(projectID, stateID int) models.TodoListOptions {
+	return models.TodoListOptions{
+		Scope:        models.ScopeAll,
+		ProjectID:    &projectID,
+		StateID:      &stateID,
+		Sort:         []models.SortField{{Field: "position"}},
+		CursorMode:   true,
+		IncludeTotal: true,
+	}
+}
+
+func (h *WorkflowHandler) boardColumn(r *http.Request, userID int, state models.WorkflowState, opts models.TodoListOptions) (*models.BoardColumn, error) {
+	page, err := h.todoRepo.GetByUserID(r.Context(), userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	column := &models.BoardColumn{
+		State:      state,
+		Todos:      page.Todos,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+	if page.Total != nil {
+		column.Total = *page.Total
+	}
+	return column, nil
+}
+
+// GetBoard returns the project's todos grouped by state, one column per state
+// in board order, each with its first ?limit= todos.
+func (h *WorkflowHandler) GetBoard(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	project, ok := h.loadProject(w, r, claims.UserID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	states, err := h.workflowRepo.ListByProject(r.Context(), project.ID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch board")
+		return
+	}
+
+	board := models.Board{Project: project, Columns: []models.BoardColumn{}}
+	for _, state := range states {
+		opts := boardColumnOptions(project.ID, state.ID)
+		todoPaging(r, &opts)
+		opts.CursorMode, opts.Cursor = true, ""
+
+		column, err := h.boardColumn(r, claims.UserID, state, opts)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch board")
+			return
+		}
+		board.Columns = append(board.Columns, *column)
+	}
+
+	utils.RespondJSON(w, http.StatusOK, board)
+}
+
+// GetBoardColumn pages through one column of the board with the cursors it returned.
+func (h *WorkflowHandler) GetBoardColumn(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	project, ok := h.loadProject(w, r, claims.UserID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	state, ok := h.loadState(w, r, project, claims.UserID)
+	if !ok {
+		return
+	}
+
+	opts := boardColumnOptions(project.ID, state.ID)
+	todoPaging(r, &opts)
+	opts.CursorMode, opts.IncludeTotal = true, true
+
+	column, err := h.boardColumn(r, claims.UserID, *state, opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch board column")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, column)
+}
+
+// GetTodoTransitions lists the todo's changes of workflow state, oldest first.
+func (h *WorkflowHandler) GetTodoTransitions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid todo ID")
+		return
+	}
+
+	if _, err := h.todoRepo.GetByID(r.Context(), todoID, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	transitions, err := h.workflowRepo.ListTransitions(r.Context(), todoID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch transitions")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, transitions)
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/pigeio/todo-api/internal/middleware"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+type WorkspaceHandler struct {
+	workspaceRepo repository.Workspace_Repository
+	validator     *validator.Validate
+}
+
+func NewWorkspaceHandler(workspaceRepo repository.Workspace_Repository) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceRepo: workspaceRepo,
+		validator:     validator.New(),
+	}
+}
+
+// loadMembership parses the {id} route variable and returns the caller's membership
+// in that workspace. It writes the error response itself and returns nil on failure.
+func (h *WorkspaceHandler) loadMembership(w http.ResponseWriter, r *http.Request, userID int) *models.WorkspaceMember {
+	workspaceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid workspace ID")
+		return nil
+	}
+
+	member, err := h.workspaceRepo.GetMember(r.Context(), workspaceID, userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Workspace not found")
+		return nil
+	}
+
+	return member
+}
+
+func (h *WorkspaceHandler) GetWorkspaces(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workspaces, err := h.workspaceRepo.GetByUserID(r.Context(), claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch workspaces")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, workspaces)
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.WorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	workspace := &models.Workspace{Name: req.Name}
+	if err := h.workspaceRepo.Create(r.Context(), workspace, claims.UserID); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to create workspace")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, workspace)
+}
+
+func (h *WorkspaceHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	membership := h.loadMembership(w, r, claims.UserID)
+	if membership == nil {
+		return
+	}
+
+	members, err := h.workspaceRepo.ListMembers(r.Context(), membership.WorkspaceID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch members")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, members)
+}
+
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	membership := h.loadMembership(w, r, claims.UserID)
+	if membership == nil {
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(r)["userId"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	target, err := h.workspaceRepo.GetMember(r.Context(), membership.WorkspaceID, userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	// Members can leave; admins can remove members; only the owner can remove admins
+	leaving := target.UserID == claims.UserID
+	if !leaving {
+		required := models.WorkspaceRoleAdmin
+		if target.Role != models.WorkspaceRoleMember {
+			required = models.WorkspaceRoleOwner
+		}
+		if !models.WorkspaceRoleAtLeast(membership.Role, required) {
+			utils.RespondError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+	}
+
+	if target.Role == models.WorkspaceRoleOwner {
+		utils.RespondError(w, http.StatusBadRequest, "The owner must transfer the workspace before leaving")
+		return
+	}
+
+	if err := h.workspaceRepo.RemoveMember(r.Context(), membership.WorkspaceID, target.UserID); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to remove member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WorkspaceHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	membership := h.loadMembership(w, r, claims.UserID)
+	if membership == nil {
+		return
+	}
+
+	if membership.Role != models.WorkspaceRoleOwner {
+		utils.RespondError(w, http.StatusForbidden, "Only the owner can transfer the workspace")
+		return
+	}
+
+	var req models.TransferOwnershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+
+	if req.UserID == claims.UserID {
+		utils.RespondError(w, http.StatusBadRequest, "You already own this workspace")
+		return
+	}
+
+	if _, err := h.workspaceRepo.GetMember(r.Context(), membership.WorkspaceID, req.UserID); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "The new owner must be a member of the workspace")
+		return
+	}
+
+	if err := h.workspaceRepo.TransferOwnership(r.Context(), membership.WorkspaceID, claims.UserID, req.UserID); err != nil {
+		if errors.Is(err, repository.ErrPersonalWorkspace) {
+			utils.RespondError(w, http.StatusConflict, "A personal workspace cannot be transferred")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to transfer workspace")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WorkspaceHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	membership := h.loadMembership(w, r, claims.UserID)
+	if membership == nil {
+		return
+	}
+
+	if !models.WorkspaceRoleAtLeast(membership.Role, models.WorkspaceRoleAdmin) {
+		utils.RespondError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var req models.WorkspaceInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "A valid email and a role of member or admin are required")
+		return
+	}
+
+	invitation := &models.WorkspaceInvitation{
+		WorkspaceID: membership.WorkspaceID,
+		Email:       strings.ToLower(req.Email),
+		Role:        req.Role,
+		InvitedBy:   claims.UserID,
+	}
+
+	if err := h.workspaceRepo.Invite(r.Context(), invitation); err != nil {
+		if errors.Is(err, repository.ErrPersonalWorkspace) {
+			utils.RespondError(w, http.StatusConflict, "Members cannot be invited to a personal workspace")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to invite member")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, invitation)
+}
+
+func (h *WorkspaceHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invitations, err := h.workspaceRepo.ListPendingInvitations(r.Context(), strings.ToLower(claims.Email))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Failed to fetch invitations")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, invitations)
+}
+
+func (h *WorkspaceHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondToInvitation(w, r, true)
+}
+
+func (h *WorkspaceHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondToInvitation(w, r, false)
+}
+
+func (h *WorkspaceHandler) respondToInvitation(w http.ResponseWriter, r *http.Request, accept bool) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invitationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	invitation, err := h.workspaceRepo.RespondToInvitation(r.Context(), invitationID, claims.UserID, strings.ToLower(claims.Email), accept)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "Invitation not found")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, invitation)
+}
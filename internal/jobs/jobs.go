@@ -0,0 +1,27 @@
+// Package jobs runs the API's periodic background work.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every runs fn right away and then every interval until ctx is cancelled.
+// Failures are logged and retried at the next tick.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Job %q failed: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+// IdempotencyKeyHeader makes a POST or PATCH safe to retry: a repeat with the
+// same key gets the first response again instead of making the change twice.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ReplayedHeader marks a response that was replayed for a repeated key.
+const ReplayedHeader = "Idempotent-Replayed"
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodyBytes caps the body of a request with a key, which is
+	// read in full to fingerprint it.
+	maxIdempotentBodyBytes = 2 << 20
+)
+
+// requestFingerprint hashes what makes two requests the same request.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{
+		r.Method,
+		r.URL.RequestURI(),
+		r.Header.Get(WorkspaceHeader),
+		r.Header.Get("Content-Type"),
+		r.Header.Get("If-Match"),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter passes a response through while keeping a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware honors IdempotencyKeyHeader on POST and PATCH, the
+// methods that aren't idempotent by themselves. The first request with a key
+// runs and its response is stored for ttl; repeats with the same key and the
+// same request get that response back, a different request with the key is
+// refused with 422, and a repeat while the first is still running gets 409.
+// Server errors aren't stored, so retrying after one runs the request again.
+// Keys are per user, so it must run after AuthMiddleware.
+func IdempotencyMiddleware(idempotencyRepo repository.Idempotency_Repository, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, ok := GetUserFromContext(r.Context())
+			if !ok {
+				utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				utils.RespondError(w, http.StatusBadRequest, "Invalid "+IdempotencyKeyHeader+" header", "keys are at most 255 characters")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+			if err != nil {
+				utils.RespondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record, err := idempotencyRepo.Begin(r.Context(), claims.UserID, key, requestFingerprint(r, body), ttl)
+			switch {
+			case errors.Is(err, repository.ErrIdempotencyKeyReused):
+				utils.RespondError(w, http.StatusUnprocessableEntity, IdempotencyKeyHeader+" was already used for a different request")
+				return
+			case errors.Is(err, repository.ErrIdempotencyInFlight):
+				w.Header().Set("Retry-After", "1")
+				utils.RespondError(w, http.StatusConflict, "A request with this "+IdempotencyKeyHeader+" is still in progress")
+				return
+			case err != nil:
+				utils.RespondError(w, http.StatusInternalServerError, "Failed to check "+IdempotencyKeyHeader)
+				return
+			}
+
+			if record.Status != nil {
+				for name, values := range record.Headers {
+					w.Header()[name] = values
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.WriteHeader(*record.Status)
+				w.Write(record.Body)
+				return
+			}
+
+			// The key is released unless a response gets stored, including when
+			// the handler panics; the client may have gone, so don't use its context
+			ctx := context.WithoutCancel(r.Context())
+			stored := false
+			defer func() {
+				if !stored {
+					if err := idempotencyRepo.Release(ctx, record.ID, claims.UserID); err != nil {
+						log.Printf("Error releasing idempotency key of user %d: %v", claims.UserID, err)
+					}
+				}
+			}()
+
+			rec := &recordingWriter{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			if err := idempotencyRepo.Complete(ctx, record.ID, claims.UserID, rec.status, w.Header().Clone(), rec.body.Bytes()); err != nil {
+				log.Printf("Error storing idempotent response of user %d: %v", claims.UserID, err)
+				return
+			}
+			stored = true
+		})
+	}
+}
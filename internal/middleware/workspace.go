@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pigeio/todo-api/internal/repository"
+	"github.com/pigeio/todo-api/internal/tenant"
+	"github.com/pigeio/todo-api/internal/utils"
+)
+
+// WorkspaceHeader selects the active workspace for a request.
+// Without it, the user's personal workspace is used.
+const WorkspaceHeader = "X-Workspace-ID"
+
+// WorkspaceMiddleware resolves the active workspace, checks that the user is a member
+// and stores it in the context for the repositories. It must run after AuthMiddleware.
+func WorkspaceMiddleware(workspaceRepo repository.Workspace_Repository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetUserFromContext(r.Context())
+			if !ok {
+				utils.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			var workspaceID int
+			if header := r.Header.Get(WorkspaceHeader); header != "" {
+				id, err := strconv.Atoi(header)
+				if err != nil || id < 1 {
+					utils.RespondError(w, http.StatusBadRequest, "Invalid "+WorkspaceHeader+" header")
+					return
+				}
+				workspaceID = id
+			} else {
+				id, err := workspaceRepo.GetPersonalID(r.Context(), claims.UserID)
+				if err != nil {
+					utils.RespondError(w, http.StatusForbidden, "No workspace selected")
+					return
+				}
+				workspaceID = id
+			}
+
+			member, err := workspaceRepo.GetMember(r.Context(), workspaceID, claims.UserID)
+			if err != nil {
+				utils.RespondError(w, http.StatusForbidden, "You are not a member of this workspace")
+				return
+			}
+
+			ctx := tenant.WithWorkspace(r.Context(), tenant.Workspace{ID: workspaceID, Role: member.Role})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import "encoding/json"
+
+// MaxBatchRequests caps the sub-requests of one POST /batch. Each of them
+// goes through the rate limiter like a separate call, so a bigger batch would
+// only fill up with 429s.
+const MaxBatchRequests = 10
+
+// BatchRequest is a list of API calls to run one after the other, as the
+// caller, in one round trip.
+type BatchRequest struct {
+	Requests []BatchSubRequest `json:"requests" validate:"required,min=1,max=10,dive"`
+}
+
+// BatchSubRequest is one call of a batch. Later sub-requests can use the
+// results of earlier ones that have a Name: ${name.field} in the path or in a
+// string of the body is replaced with that field of the earlier response body
+// (nested fields and array elements are separated by dots, as in
+// ${created.todo.id}). A string that is nothing but the reference takes on
+// the field's JSON type. Headers may not set Authorization; the batch's own
+// is always used.
+type BatchSubRequest struct {
+	Name    string            `json:"name" validate:"omitempty,max=50"`
+	Method  string            `json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	Path    string            `json:"path" validate:"required,startswith=/,max=2000"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// BatchSubResponse is the response to one sub-request, in request order. A
+// body that isn't JSON is returned as a JSON string.
+type BatchSubResponse struct {
+	Name    string            `json:"name,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
@@ -0,0 +1,83 @@
+package models
+
+// Bulk operations for POST /todos/bulk
+const (
+	BulkCreate   = "create"
+	BulkUpdate   = "update"
+	BulkComplete = "complete"
+	BulkMove     = "move"
+	BulkDelete   = "delete"
+)
+
+// Bulk modes: atomic applies every item or none, best_effort applies the
+// items that can be and reports the rest.
+const (
+	BulkAtomic     = "atomic"
+	BulkBestEffort = "best_effort"
+)
+
+// MaxBulkItems caps the items of one bulk request: each ID of an operation
+// counts as one, and so does each create.
+const MaxBulkItems = 500
+
+// BulkTodoRequest is a list of operations applied in order in one transaction.
+type BulkTodoRequest struct {
+	// Mode is atomic (the default) or best_effort
+	Mode       string          `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Operations []BulkOperation `json:"operations" validate:"required,min=1,dive"`
+}
+
+// BulkOperation applies one change to each of IDs, or creates one todo. Which
+// of the other fields it reads depends on Op:
+//
+//   - create: Todo
+//   - update: Changes, as in PUT /todos/{id}
+//   - complete: Completed, true when left out
+//   - move: ProjectID; 0 takes the todos out of their project
+//   - delete: nothing; the todos go to the trash
+type BulkOperation struct {
+	Op        string             `json:"op" validate:"required,oneof=create update complete move delete"`
+	IDs       []int              `json:"ids"`
+	Todo      *CreateTodoRequest `json:"todo"`
+	Changes   *UpdateTodoRequest `json:"changes"`
+	Completed *bool              `json:"completed"`
+	ProjectID *int               `json:"project_id"`
+}
+
+// BulkItemResult is the outcome of one item: an operation applied to one ID,
+// or one create. Status is the HTTP status the item would have had on its own;
+// in atomic mode, items that were fine but rolled back because another one
+// failed get 424 Failed Dependency.
+type BulkItemResult struct {
+	Operation int           `json:"operation"`
+	Op        string        `json:"op"`
+	ID        *int          `json:"id,omitempty"`
+	Status    int           `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Todo      *Todo         `json:"todo,omitempty"`
+	Warnings  []TodoWarning `json:"warnings,omitempty"`
+}
+
+// BulkTodoResponse reports every item in request order. Undo reverts all the
+// items that were applied.
+type BulkTodoResponse struct {
+	Mode      string           `json:"mode"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+	Undo      *UndoInfo        `json:"undo,omitempty"`
+}
+
+// BulkWrite is one change a bulk request makes to the database: inserting
+// Todo, saving it, or moving it to the trash.
+type BulkWrite struct {
+	Op   string
+	Todo *Todo
+}
+
+// Bulk writes
+const (
+	BulkWriteInsert = "insert"
+	BulkWriteUpdate = "update"
+	BulkWriteTrash  = "trash"
+)
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type Comment struct {
+	ID        int           `json:"id"`
+	TodoID    int           `json:"todo_id"`
+	UserID    int           `json:"-"`
+	Author    CommentAuthor `json:"author"`
+	Body      string        `json:"body"`
+	Edited    bool          `json:"edited"`
+	EditedAt  *time.Time    `json:"edited_at,omitempty"`
+	Deleted   bool          `json:"deleted"`
+	DeletedAt *time.Time    `json:"-"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type CommentAuthor struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// CommentRequest is used for both creating and editing a comment.
+// The body is stored as raw markdown; rendering is left to the client.
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+type CommentListResponse struct {
+	Data  []Comment `json:"data"`
+	Page  int       `json:"page"`
+	Limit int       `json:"limit"`
+	Total int       `json:"total"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "net/http"
+
+// IdempotencyRecord is what is kept of a request sent with an Idempotency-Key.
+// Status is nil until the request has finished; then Status, Headers and Body
+// are its response.
+type IdempotencyRecord struct {
+	ID          int64
+	UserID      int
+	Key         string
+	Fingerprint string
+	Status      *int
+	Headers     http.Header
+	Body        []byte
+}
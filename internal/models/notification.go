@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Notification types
+const (
+	NotificationTodoAssigned   = "todo_assigned"
+	NotificationTodoUnassigned = "todo_unassigned"
+)
+
+type Notification struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"-"`
+	Type      string     `json:"type"`
+	TodoID    *int       `json:"todo_id,omitempty"`
+	ActorID   *int       `json:"actor_id,omitempty"`
+	Message   string     `json:"message"`
+	Read      bool       `json:"read"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type NotificationListResponse struct {
+	Data  []Notification `json:"data"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Total int            `json:"total"`
+}
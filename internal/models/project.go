@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Project struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	UserID      int       `json:"-"`
+	Name        string    `json:"name"`
+	Role        string    `json:"role,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ProjectRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
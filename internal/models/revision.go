@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision actions
+const (
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionDelete   = "delete"  // moved to the trash
+	RevisionRestore  = "restore" // taken out of the trash
+	RevisionSnapshot = "snapshot"
+)
+
+// TodoRevision is one entry of a todo's history: which fields a create,
+// update, delete or restore changed, and who made it (nil for background
+// jobs). Snapshot holds every tracked field as it was after the change.
+// A snapshot revision records how a todo looked when history began.
+type TodoRevision struct {
+	ID        int64           `json:"id"`
+	TodoID    int             `json:"todo_id"`
+	Revision  int             `json:"revision"`
+	Action    string          `json:"action"`
+	UserID    *int            `json:"user_id"`
+	Changes   []FieldChange   `json:"changes"`
+	Snapshot  json.RawMessage `json:"snapshot,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	// TodoTitle is the todo's current title, set in the activity feed
+	TodoTitle string `json:"todo_title,omitempty"`
+}
+
+// FieldChange is one field's value before and after a change; values are as
+// stored (null when empty).
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// TodoSnapshot is the part of a revision's snapshot that reverting restores.
+// Archiving and trashing are not reverted.
+type TodoSnapshot struct {
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
+	Completed   bool    `json:"completed"`
+	ProjectID   *int    `json:"project_id"`
+	AssigneeID  *int    `json:"assignee_id"`
+	StateID     *int    `json:"state_id"`
+}
+
+// RevertTodoRequest names the revision to go back to.
+type RevertTodoRequest struct {
+	Revision int `json:"revision" validate:"required,min=1"`
+}
+
+// RevisionListResponse is a page of revisions, newest first.
+type RevisionListResponse struct {
+	Data  []TodoRevision `json:"data"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Total int            `json:"total"`
+}
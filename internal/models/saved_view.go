@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// SavedView is a named todo filter and sort order. User views have an ID;
+// built-in system views have a Key instead and cannot be changed.
+type SavedView struct {
+	ID          int       `json:"id,omitempty"`
+	Key         string    `json:"key,omitempty"`
+	WorkspaceID int       `json:"workspace_id,omitempty"`
+	UserID      int       `json:"-"`
+	Name        string    `json:"name"`
+	Filter      string    `json:"filter"`
+	Sort        string    `json:"sort"`
+	System      bool      `json:"system"`
+	Count       *int      `json:"count,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+	UpdatedAt   time.Time `json:"updated_at,omitzero"`
+}
+
+type SavedViewRequest struct {
+	Name   string `json:"name" validate:"required,max=100"`
+	Filter string `json:"filter" validate:"max=1000"`
+	Sort   string `json:"sort" validate:"max=100"`
+}
+
+// SystemViews are available to every user in every workspace. They run over
+// everything the user can see, like user views.
+var SystemViews = []SavedView{
+	{Key: "inbox", Name: "Inbox", Filter: "completed:false AND owner:me AND project:none"},
+	{Key: "today", Name: "Today", Filter: "completed:false AND (created_at:today OR updated_at:today)", Sort: "-updated_at"},
+	{Key: "assigned-to-me", Name: "Assigned to me", Filter: "completed:false AND assignee:me"},
+	{Key: "waiting-on-others", Name: "Waiting on others", Filter: "completed:false AND owner:me AND assignee!=me AND assignee!=none"},
+	{Key: "recently-completed", Name: "Recently completed", Filter: "completed:true AND updated_at>=-7d", Sort: "-updated_at"},
+}
+
+// SystemView returns the system view with the given key.
+func SystemView(key string) (SavedView, bool) {
+	for _, view := range SystemViews {
+		if view.Key == key {
+			view.System = true
+			return view, true
+		}
+	}
+	return SavedView{}, false
+}
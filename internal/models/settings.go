@@ -0,0 +1,19 @@
+package models
+
+// UserSettings holds per-user preferences.
+type UserSettings struct {
+	// SearchLanguage is the Postgres text search configuration used to
+	// stem the user's todos and search queries (e.g. "english", "german", "simple").
+	SearchLanguage string `json:"search_language" validate:"required,max=63"`
+	// BlockedCompletion is what happens when the user completes a todo whose
+	// blockers are still open: BlockedCompletionWarn (the default) or BlockedCompletionReject.
+	BlockedCompletion string `json:"blocked_completion" validate:"omitempty,oneof=warn reject"`
+	// AutoArchiveDays archives the user's completed todos once they have gone
+	// that many days without a change; nil turns automatic archiving off.
+	AutoArchiveDays *int `json:"auto_archive_days" validate:"omitempty,min=1,max=3650"`
+}
+
+const (
+	BlockedCompletionWarn   = "warn"   // complete it, with a warning in the response
+	BlockedCompletionReject = "reject" // refuse with 409 Conflict
+)
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Roles a user can hold on a todo or project, from least to most privileged.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// Share types, matching which column of the shares row is set.
+const (
+	ShareTypeTodo    = "todo"
+	ShareTypeProject = "project"
+)
+
+// Invitation states. Only accepted shares grant access.
+const (
+	ShareStatusPending  = "pending"
+	ShareStatusAccepted = "accepted"
+	ShareStatusDeclined = "declined"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// RoleFromRank converts the role_rank column of the access views into a role name.
+func RoleFromRank(rank int) string {
+	for role, r := range roleRanks {
+		if r == rank {
+			return role
+		}
+	}
+	return ""
+}
+
+// RoleRank returns the numeric rank of a role, or 0 for an unknown role.
+func RoleRank(role string) int {
+	return roleRanks[role]
+}
+
+// RoleAtLeast reports whether role grants at least the privileges of min.
+func RoleAtLeast(role, min string) bool {
+	return RoleRank(role) > 0 && RoleRank(role) >= RoleRank(min)
+}
+
+type Share struct {
+	ID           int        `json:"id"`
+	ResourceType string     `json:"resource_type"`
+	ResourceID   int        `json:"resource_id"`
+	UserID       int        `json:"user_id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	Status       string     `json:"status"`
+	InvitedBy    int        `json:"invited_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RespondedAt  *time.Time `json:"responded_at,omitempty"`
+}
+
+type ShareRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=viewer editor owner"`
+}
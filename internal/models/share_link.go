@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type ShareLink struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"-"`
+	ResourceType   string     `json:"resource_type"`
+	ResourceID     int        `json:"resource_id"`
+	Token          string     `json:"token,omitempty"` // only returned when the link is created
+	TokenHash      string     `json:"-"`
+	PasswordHash   *string    `json:"-"`
+	HasPassword    bool       `json:"has_password"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	AccessCount    int        `json:"access_count"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type CreateShareLinkRequest struct {
+	ResourceType string     `json:"resource_type" validate:"required,oneof=todo project"`
+	ResourceID   int        `json:"resource_id" validate:"required"`
+	Password     string     `json:"password" validate:"omitempty,min=6"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+// PublicTodo is the sanitized todo shown through a share link.
+// It deliberately carries no IDs, user IDs or emails.
+type PublicTodo struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Completed   bool      `json:"completed"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type PublicProject struct {
+	Name  string       `json:"name"`
+	Todos []PublicTodo `json:"todos"`
+}
+
+// SharedView is the response of GET /shared/{token}; exactly one of Todo or Project is set.
+type SharedView struct {
+	ResourceType string         `json:"resource_type"`
+	Todo         *PublicTodo    `json:"todo,omitempty"`
+	Project      *PublicProject `json:"project,omitempty"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SortField is one key of a listing order, as in ?sort=-updated_at,title:nulls_first.
+type SortField struct {
+	Field      string
+	Desc       bool
+	NullsFirst bool
+}
+
+// TodoSortFields are the fields todo listings can be sorted by. rank, the
+// relevance of a search result, is only available when searching.
+var TodoSortFields = []string{"created_at", "updated_at", "title", "completed", "project", "assignee", "position", "id", "rank"}
+
+// MaxSortFields caps the number of keys in one order.
+const MaxSortFields = 5
+
+// ParseSort parses a comma-separated list of fields, each optionally prefixed
+// with - (descending) or + (ascending, the default) and suffixed with
+// :nulls_first or :nulls_last (the default) to place empty values.
+func ParseSort(s string, fields []string) ([]SortField, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	terms := strings.Split(s, ",")
+	if len(terms) > MaxSortFields {
+		return nil, fmt.Errorf("at most %d sort fields are allowed", MaxSortFields)
+	}
+
+	sort := make([]SortField, 0, len(terms))
+	seen := map[string]bool{}
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, errors.New("empty sort field")
+		}
+
+		var field SortField
+		name, nulls, hasNulls := strings.Cut(term, ":")
+		switch {
+		case strings.HasPrefix(name, "-"):
+			field.Desc = true
+			name = name[1:]
+		case strings.HasPrefix(name, "+"):
+			name = name[1:]
+		}
+
+		if hasNulls {
+			switch nulls {
+			case "nulls_first":
+				field.NullsFirst = true
+			case "nulls_last":
+			default:
+				return nil, fmt.Errorf("invalid null ordering %q in %q; use nulls_first or nulls_last", nulls, term)
+			}
+		}
+
+		known := false
+		for _, f := range fields {
+			known = known || f == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown sort field %q; use one of %s", name, strings.Join(fields, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("sort field %q is repeated", name)
+		}
+		seen[name] = true
+
+		field.Field = name
+		sort = append(sort, field)
+	}
+
+	return sort, nil
+}
+
+// LegacySortBy translates the old ?sort_by= values into a sort.
+func LegacySortBy(sortBy string) []SortField {
+	switch sortBy {
+	case "title":
+		return []SortField{{Field: "title"}}
+	case "updated_at":
+		return []SortField{{Field: "updated_at", Desc: true}}
+	}
+	return nil
+}
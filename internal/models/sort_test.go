@@ -0,0 +1,27 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	got, err := ParseSort("-updated_at, +title:nulls_first,project:nulls_last", TodoSortFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SortField{
+		{Field: "updated_at", Desc: true},
+		{Field: "title", NullsFirst: true},
+		{Field: "project"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSort = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"priority", "title,", "title,-title", "title:nulls_middle", "a,b,c,d,e,f", "-"} {
+		if _, err := ParseSort(bad, TodoSortFields); err == nil {
+			t.Errorf("ParseSort(%q): want error", bad)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Changes a client sends to POST /sync
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// How POST /sync settles a field both the client and the server changed since
+// the client's base version: the later change wins (the default), or always
+// the server's or the client's.
+const (
+	SyncLastWriterWins = "last_writer_wins"
+	SyncServerWins     = "server_wins"
+	SyncClientWins     = "client_wins"
+)
+
+// Winners of a sync conflict
+const (
+	SyncWinnerClient = "client"
+	SyncWinnerServer = "server"
+)
+
+// SyncDeletedField names a conflict between a client deleting a todo and the
+// server changing it.
+const SyncDeletedField = "deleted"
+
+// MaxSyncChanges caps the client changes of one sync request.
+const MaxSyncChanges = 500
+
+// SyncPageSize caps the server changes of one sync response; HasMore asks the
+// client to sync again with the new token for the rest.
+const SyncPageSize = 500
+
+// SyncRequest is a client's queued local changes plus the token of its last
+// sync (empty for the first one).
+type SyncRequest struct {
+	SyncToken          string       `json:"sync_token"`
+	ConflictResolution string       `json:"conflict_resolution" validate:"omitempty,oneof=last_writer_wins server_wins client_wins"`
+	Changes            []SyncChange `json:"changes" validate:"max=500,dive"`
+}
+
+// SyncChange is one change made offline, in the order it was made.
+type SyncChange struct {
+	// ClientID is the client's name for the change, echoed in its result; for
+	// a create it ties the client's local todo to its new ID.
+	ClientID string `json:"client_id" validate:"required,max=100"`
+	Op       string `json:"op" validate:"required,oneof=create update delete"`
+	// ID and BaseVersion (the version the change was made on) are for update and delete
+	ID          int `json:"id"`
+	BaseVersion int `json:"base_version"`
+	// Fields are the fields the change set, as in TodoPatchDocument; null
+	// clears the description, project, assignee or state.
+	Fields map[string]json.RawMessage `json:"fields"`
+	// ChangedAt is when the change was made on the device
+	ChangedAt time.Time `json:"changed_at" validate:"required"`
+}
+
+// SyncConflict is a field changed both by the client and, since the client's
+// base version, on the server, to a different value.
+type SyncConflict struct {
+	Field           string          `json:"field"`
+	ClientValue     json.RawMessage `json:"client_value"`
+	ServerValue     json.RawMessage `json:"server_value"`
+	ServerChangedAt time.Time       `json:"server_changed_at"`
+	Winner          string          `json:"winner"`
+}
+
+// SyncChangeResult is the outcome of one client change. Status is the HTTP
+// status the change would have had as its own request.
+type SyncChangeResult struct {
+	ClientID  string         `json:"client_id"`
+	Op        string         `json:"op"`
+	ID        *int           `json:"id,omitempty"`
+	Status    int            `json:"status"`
+	Error     string         `json:"error,omitempty"`
+	Conflicts []SyncConflict `json:"conflicts,omitempty"`
+	Warnings  []TodoWarning  `json:"warnings,omitempty"`
+}
+
+// SyncTombstone is a todo the client should drop: deleted, or in the trash.
+type SyncTombstone struct {
+	ID        int       `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncPage is a page of the server changes since a sync token. Full is set
+// when the sync started without a token: the pages then hold every todo the
+// user can see, and no tombstones.
+type SyncPage struct {
+	Todos     []Todo
+	Deleted   []SyncTombstone
+	SyncToken string
+	HasMore   bool
+	Full      bool
+}
+
+// SyncResponse reports the client's changes in order, then the todos that
+// changed on the server since the client's token, the client's own changes
+// included, and a token for the next sync.
+type SyncResponse struct {
+	Results   []SyncChangeResult `json:"results"`
+	Todos     []Todo             `json:"todos"`
+	Deleted   []SyncTombstone    `json:"deleted"`
+	SyncToken string             `json:"sync_token"`
+	HasMore   bool               `json:"has_more"`
+	// Full means the client should drop the todos it has that aren't in
+	// Todos of this page or the pages that follow it
+	Full bool `json:"full"`
+}
+
+// TodoFieldChange is a field a revision of a todo changed.
+type TodoFieldChange struct {
+	Field     string
+	Version   int
+	ChangedAt time.Time
+}
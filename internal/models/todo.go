@@ -1,33 +1,251 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/pigeio/todo-api/internal/filter"
+)
 
 type Todo struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"-"`
-	Title       string    `json:"title" validate:"required"`
-	Description string    `json:"description"`
-	Completed   bool      `json:"completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int    `json:"id"`
+	WorkspaceID int    `json:"workspace_id"`
+	UserID      int    `json:"-"`
+	ProjectID   *int   `json:"project_id"`
+	AssigneeID  *int   `json:"assignee_id"`
+	Title       string `json:"title" validate:"required"`
+	Description string `json:"description"`
+	Completed   bool   `json:"completed"`
+	// StateID is the todo's workflow state (see WorkflowState); only todos in a project have one
+	StateID *int    `json:"state_id"`
+	State   *string `json:"state"`
+	// BlockedBy lists the open todos this one depends on; Blocked is set while there are any
+	Blocked   bool  `json:"blocked"`
+	BlockedBy []int `json:"blocked_by"`
+	// Position is the todo's place in the manual order (sort=position); compare keys byte by byte
+	Position     string    `json:"position"`
+	CommentCount int       `json:"comment_count"`
+	Role         string    `json:"role,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	// Version goes up with every change to the todo's fields, but not when
+	// rebalancing rewrites position keys (see migrations/000024); the todo's
+	// ETag is the quoted version
+	Version int `json:"version"`
+	// ArchivedAt is set while the todo is archived
+	ArchivedAt *time.Time `json:"archived_at"`
+	// DeletedAt is only set on todos in the trash (GET /trash)
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Search is only set on results of a search (GET /todos?q=)
+	Search *TodoSearchMatch `json:"search,omitempty"`
+}
+
+// TodoSearchMatch explains why a todo matched a search. The snippets are
+// HTML: the text escaped, with matched words wrapped in <mark></mark> (fuzzy
+// search marks none).
+type TodoSearchMatch struct {
+	Rank        float32 `json:"rank"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
 }
 
 type CreateTodoRequest struct {
 	Title       string `json:"title" validate:"required"`
 	Description string `json:"description"`
+	ProjectID   *int   `json:"project_id"`
+	AssigneeID  *int   `json:"assignee_id"`
+	// StateID puts the todo in one of its project's workflow states
+	StateID *int `json:"state_id"`
 }
 
 type UpdateTodoRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Completed   *bool  `json:"completed"`
+	// ProjectID moves the todo into a project; 0 takes it out of its project.
+	ProjectID *int `json:"project_id"`
+	// AssigneeID hands the todo to a collaborator; 0 unassigns it.
+	AssigneeID *int `json:"assignee_id"`
+	// StateID moves the todo to another state of its project, completing or
+	// reopening it to match. Without it, Completed picks a matching state.
+	StateID *int `json:"state_id"`
+}
+
+// TodoPatchDocument is the todo as PATCH /todos/{id} sees it: the document a
+// merge patch or JSON Patch is applied to. Unlike UpdateTodoRequest, every
+// field is taken as given, so null (or removing the member) clears the
+// description, project, assignee and state.
+type TodoPatchDocument struct {
+	Title       string `json:"title" validate:"required"`
+	Description string `json:"description"`
+	Completed   *bool  `json:"completed" validate:"required"`
+	ProjectID   *int   `json:"project_id"`
+	AssigneeID  *int   `json:"assignee_id"`
+	StateID     *int   `json:"state_id"`
+}
+
+// PatchDocumentFor returns the todo's patchable fields.
+func PatchDocumentFor(todo *Todo) TodoPatchDocument {
+	completed := todo.Completed
+	return TodoPatchDocument{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   &completed,
+		ProjectID:   todo.ProjectID,
+		AssigneeID:  todo.AssigneeID,
+		StateID:     todo.StateID,
+	}
+}
+
+// Listing scopes for GET /todos?scope=
+const (
+	ScopeOwn    = "own"    // todos the user created
+	ScopeShared = "shared" // other users' todos the user can see, through a share or a project
+	ScopeAll    = "all"    // everything the user can see
+)
+
+// TodoListOptions carries the query parameters of a todo listing down to the repository.
+type TodoListOptions struct {
+	Page  int
+	Limit int
+	// Status is completed, pending, archived, or the name of a workflow state.
+	// Archived todos are only listed with status archived.
+	Status string
+	// ProjectID and StateID limit the listing to one project, and one state of it (boards)
+	ProjectID *int
+	StateID   *int
+	// Sort orders the listing; empty means newest first, or most relevant first when searching
+	Sort  []SortField
+	Scope string
+	// AssigneeID limits the listing to todos assigned to that user,
+	// Unassigned to todos nobody is assigned to.
+	AssigneeID *int
+	Unassigned bool
+	// Query is a search in SearchMode; results are ordered by relevance unless Sort is set.
+	Query      string
+	SearchMode string
+	// Actionable limits the listing to open todos that aren't blocked
+	Actionable bool
+	// Filter is a parsed GET /todos?filter= expression (nil when absent)
+	Filter filter.Expr
+	// CursorMode pages by Cursor (empty for the first page) instead of Page.
+	// It only counts the total if IncludeTotal is set.
+	CursorMode   bool
+	Cursor       string
+	IncludeTotal bool
+}
+
+// TodoPage is one page of a todo listing. Total is nil when it wasn't counted;
+// the cursors are empty when there is no page in that direction.
+type TodoPage struct {
+	Todos      []Todo
+	Total      *int
+	NextCursor string
+	PrevCursor string
+}
+
+// TodoFilterFields are the fields a todo filter expression may use.
+var TodoFilterFields = filter.Fields{
+	"completed":   {Type: filter.Bool},
+	"title":       {Type: filter.Text},
+	"description": {Type: filter.Text},
+	"project":     {Type: filter.Number, Nullable: true},
+	"assignee":    {Type: filter.User, Nullable: true},
+	"state":       {Type: filter.Text, Nullable: true},
+	"blocked":     {Type: filter.Bool},
+	"owner":       {Type: filter.User},
+	"created_at":  {Type: filter.Time},
+	"updated_at":  {Type: filter.Time},
+}
+
+// Search modes for GET /todos?q=&search_mode=
+const (
+	SearchModeFullText = "fulltext" // stemmed words with prefix matching on the last one (default)
+	SearchModeFuzzy    = "fuzzy"    // trigram similarity; tolerates typos and partial words
+)
+
+// SimilarTodo is a todo that resembles another one, with how closely (0 to 1).
+type SimilarTodo struct {
+	Todo
+	Similarity float32 `json:"similarity"`
+}
+
+// TodoWarning flags something the client may want to tell the user about a
+// request that nonetheless succeeded.
+type TodoWarning struct {
+	Code       string        `json:"code"`
+	Message    string        `json:"message"`
+	Duplicates []SimilarTodo `json:"duplicates,omitempty"`
+	BlockedBy  []int         `json:"blocked_by,omitempty"`
+}
+
+// Warning codes
+const (
+	WarningPossibleDuplicate = "possible_duplicate"
+	WarningBlocked           = "blocked"
+)
+
+// MoveTodoRequest places a todo right before or right after another one.
+type MoveTodoRequest struct {
+	Before *int `json:"before"`
+	After  *int `json:"after"`
+}
+
+// CreateTodoResponse is the created todo plus any warnings about it.
+type CreateTodoResponse struct {
+	*Todo
+	Warnings []TodoWarning `json:"warnings,omitempty"`
+}
+
+// UpdateTodoResponse is the saved todo plus any warnings about the change.
+type UpdateTodoResponse struct {
+	*Todo
+	Warnings []TodoWarning `json:"warnings,omitempty"`
+	// Undo puts the todo back the way it was before the update
+	Undo *UndoInfo `json:"undo,omitempty"`
+}
+
+// Listing statuses for GET /todos?status=; any other status names a workflow state
+const (
+	StatusCompleted = "completed"
+	StatusPending   = "pending"
+	StatusArchived  = "archived"
+)
+
+// ArchiveResponse reports how many todos a bulk archive archived.
+type ArchiveResponse struct {
+	Archived int `json:"archived"`
+}
+
+// DependencyRequest makes a todo depend on (be blocked by) another one.
+type DependencyRequest struct {
+	BlockerID int `json:"blocker_id" validate:"required"`
+}
+
+// TodoDependencies are the todos a todo is blocked by, and the ones it blocks.
+type TodoDependencies struct {
+	BlockedBy []Todo `json:"blocked_by"`
+	Blocking  []Todo `json:"blocking"`
+}
+
+// TrashListResponse is a page of the user's trash, with how long todos stay
+// there before they are purged.
+type TrashListResponse struct {
+	Data          []Todo `json:"data"`
+	Page          int    `json:"page"`
+	Limit         int    `json:"limit"`
+	Total         int    `json:"total"`
+	RetentionDays int    `json:"retention_days"`
 }
 
+// TodoListResponse is a page of todos. Page is only set when paging by page
+// number, and Total is left out in cursor mode unless include_total=true.
 type TodoListResponse struct {
-	Data  []Todo `json:"data"`
-	Page  int    `json:"page"`
-	Limit int    `json:"limit"`
-	Total int    `json:"total"`
+	Data       []Todo `json:"data"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Total      *int   `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type ErrorResponse struct {
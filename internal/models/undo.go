@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// UndoWindow is how long an undo token can be redeemed.
+const UndoWindow = 5 * time.Minute
+
+// Undo actions: what the change being undone did
+const (
+	UndoActionUpdate = "update"
+	UndoActionDelete = "delete"
+	UndoActionBulk   = "bulk"
+)
+
+// Undo is a redeemable record of the state todos were in before a change.
+// It belongs to the user who made the change and can be used once.
+type Undo struct {
+	ID          int
+	TokenHash   string
+	UserID      int
+	WorkspaceID int
+	Action      string
+	Todos       []UndoSnapshot
+	ExpiresAt   time.Time
+}
+
+// UndoSnapshot is everything about a todo that undoing a change restores.
+type UndoSnapshot struct {
+	TodoID      int        `json:"todo_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	ProjectID   *int       `json:"project_id"`
+	AssigneeID  *int       `json:"assignee_id"`
+	StateID     *int       `json:"state_id"`
+	Position    string     `json:"position"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+	// Version is the version the change left the todo at; a todo that has
+	// changed again since is not undone
+	Version int `json:"version"`
+	// Created marks a todo the change created; undoing it moves it to the trash
+	Created bool `json:"created,omitempty"`
+}
+
+// SnapshotForUndo captures the todo as it is now, before it gets changed.
+// The caller sets Version once the change is made.
+func SnapshotForUndo(todo *Todo) UndoSnapshot {
+	return UndoSnapshot{
+		TodoID:      todo.ID,
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		ProjectID:   todo.ProjectID,
+		AssigneeID:  todo.AssigneeID,
+		StateID:     todo.StateID,
+		Position:    todo.Position,
+		ArchivedAt:  todo.ArchivedAt,
+	}
+}
+
+// UndoInfo is the token returned with a change that can be undone through
+// POST /undo/{token} until ExpiresAt.
+type UndoInfo struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// A DELETE answers 204 No Content, so it returns its undo token in headers:
+// the token, and when it expires in HTTP date format.
+const (
+	UndoTokenHeader   = "Undo-Token"
+	UndoExpiresHeader = "Undo-Expires"
+)
+
+// UndoResponse lists the todos an undo put back, or trashed again if the change
+// created them, and the ones it left as they are: changed again since, no
+// longer the user's to restore, or purged in the meantime.
+type UndoResponse struct {
+	Restored []int `json:"restored"`
+	Skipped  []int `json:"skipped"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// WorkflowState is one column of a project's board. Todos in a state marked
+// IsDone are completed; moving a todo into or out of such a state completes
+// or reopens it.
+type WorkflowState struct {
+	ID        int       `json:"id"`
+	ProjectID int       `json:"project_id"`
+	Name      string    `json:"name"`
+	Position  int       `json:"position"`
+	IsDone    bool      `json:"is_done"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WorkflowStateRequest creates or changes a state. Position is 1-based; left
+// out, a new state goes last and an existing one stays where it is.
+type WorkflowStateRequest struct {
+	Name     string `json:"name" validate:"required,max=50"`
+	IsDone   bool   `json:"is_done"`
+	Position *int   `json:"position" validate:"omitempty,min=1"`
+}
+
+// DefaultWorkflowStates are the states every new project starts with.
+var DefaultWorkflowStates = []WorkflowState{
+	{Name: "Backlog", Position: 1},
+	{Name: "In Progress", Position: 2},
+	{Name: "Review", Position: 3},
+	{Name: "Done", Position: 4, IsDone: true},
+}
+
+// StateFor picks the state a todo lands in when it only says whether it is
+// completed: the first state (in board order) that agrees with it. It returns
+// nil if the project has no states.
+func StateFor(states []WorkflowState, completed bool) *WorkflowState {
+	for i := range states {
+		if states[i].IsDone == completed {
+			return &states[i]
+		}
+	}
+	if len(states) > 0 {
+		return &states[0]
+	}
+	return nil
+}
+
+// StateTransition records a todo moving from one state to another. The names
+// are kept as they were at the time; the IDs are nil once a state is deleted.
+// From is nil when the todo entered its first state.
+type StateTransition struct {
+	ID          int       `json:"id"`
+	TodoID      int       `json:"todo_id"`
+	FromStateID *int      `json:"from_state_id"`
+	ToStateID   *int      `json:"to_state_id"`
+	FromState   *string   `json:"from_state"`
+	ToState     *string   `json:"to_state"`
+	UserID      *int      `json:"user_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BoardColumn is one state of a board with the first page of its todos, in
+// manual order. NextCursor pages through the rest of the column
+// (GET /projects/{id}/board/{stateId}?cursor=).
+type BoardColumn struct {
+	State      WorkflowState `json:"state"`
+	Todos      []Todo        `json:"todos"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+}
+
+// Board is a project's todos grouped by workflow state.
+type Board struct {
+	Project *Project      `json:"project"`
+	Columns []BoardColumn `json:"columns"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestStateFor(t *testing.T) {
+	states := []WorkflowState{
+		{ID: 1, Name: "Backlog"},
+		{ID: 2, Name: "In Progress"},
+		{ID: 3, Name: "Done", IsDone: true},
+		{ID: 4, Name: "Won't do", IsDone: true},
+	}
+
+	if got := StateFor(states, false); got == nil || got.ID != 1 {
+		t.Errorf("StateFor(open) = %v, want Backlog", got)
+	}
+	if got := StateFor(states, true); got == nil || got.ID != 3 {
+		t.Errorf("StateFor(done) = %v, want Done", got)
+	}
+	if got := StateFor(states[:2], true); got == nil || got.ID != 1 {
+		t.Errorf("StateFor without done states = %v, want the first state", got)
+	}
+	if got := StateFor(nil, false); got != nil {
+		t.Errorf("StateFor(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Workspace roles, from least to most privileged. Admins manage members;
+// the single owner can additionally hand the workspace over to someone else.
+const (
+	WorkspaceRoleMember = "member"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleOwner  = "owner"
+)
+
+var workspaceRoleRanks = map[string]int{
+	WorkspaceRoleMember: 1,
+	WorkspaceRoleAdmin:  2,
+	WorkspaceRoleOwner:  3,
+}
+
+// WorkspaceRoleAtLeast reports whether role grants at least the privileges of min.
+func WorkspaceRoleAtLeast(role, min string) bool {
+	return workspaceRoleRanks[role] > 0 && workspaceRoleRanks[role] >= workspaceRoleRanks[min]
+}
+
+type Workspace struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Personal  bool      `json:"personal"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int       `json:"workspace_id"`
+	UserID      int       `json:"user_id"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type WorkspaceInvitation struct {
+	ID            int        `json:"id"`
+	WorkspaceID   int        `json:"workspace_id"`
+	WorkspaceName string     `json:"workspace_name"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	Status        string     `json:"status"`
+	InvitedBy     int        `json:"invited_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RespondedAt   *time.Time `json:"responded_at,omitempty"`
+}
+
+type WorkspaceRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type WorkspaceInvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=member admin"`
+}
+
+type TransferOwnershipRequest struct {
+	UserID int `json:"user_id" validate:"required"`
+}
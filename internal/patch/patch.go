@@ -0,0 +1,394 @@
+// Package patch applies the two standard formats for partial updates of a
+// JSON document: JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902).
+//
+// Both take the whole document and return the patched one. Nothing is
+// applied to the caller's data until a patch has been applied in full, so a
+// JSON Patch that fails part way, or whose test operation fails, changes
+// nothing. Checking that the result is still a valid document is up to the
+// caller.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Media types of the two formats, as sent in Content-Type
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch means the patch is malformed or refers to a location the
+	// document doesn't have.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed means a test operation of a JSON Patch didn't match.
+	ErrTestFailed = errors.New("test operation failed")
+)
+
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// Merge applies an RFC 7396 merge patch to doc: members of the patch replace
+// those of the document, objects are merged recursively and null removes a
+// member.
+func Merge(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = map[string]any{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+		} else {
+			object[key] = merge(object[key], value)
+		}
+	}
+	return object
+}
+
+// Operation is one step of a JSON Patch. Value is nil when the operation has
+// none, which is different from a JSON null.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies an RFC 6902 JSON Patch, a list of operations (add, remove,
+// replace, move, copy and test), to doc in order.
+func Apply(doc, patch []byte) ([]byte, error) {
+	root, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: a JSON Patch is an array of operations", ErrInvalidPatch)
+	}
+
+	for i, op := range ops {
+		if root, err = apply(root, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func apply(root any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (any, error) {
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: value is required", ErrInvalidPatch)
+		}
+		return decode(op.Value)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, v)
+
+	case "remove":
+		root, _, err := remove(root, path)
+		return root, err
+
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		root, _, err := remove(root, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, v)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var v any
+		if op.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+			}
+			root, v, err = remove(root, from)
+		} else {
+			v, err = get(root, from)
+			if err == nil {
+				v, err = clone(v)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, v)
+
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		current, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, v) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses an array index token; end allows the index one past the
+// last element, where add appends.
+func arrayIndex(token string, length int, end bool) (int, error) {
+	if end && token == "-" {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalidPatch, token)
+	}
+	if i > length || (i == length && !end) {
+		return 0, fmt.Errorf("%w: index %d is out of range", ErrInvalidPatch, i)
+	}
+	return i, nil
+}
+
+func notFound(path []string) error {
+	return fmt.Errorf("%w: /%s does not exist", ErrInvalidPatch, strings.Join(path, "/"))
+}
+
+func get(node any, path []string) (any, error) {
+	for depth, token := range path {
+		switch c := node.(type) {
+		case map[string]any:
+			child, ok := c[token]
+			if !ok {
+				return nil, notFound(path[:depth+1])
+			}
+			node = child
+		case []any:
+			i, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			node = c[i]
+		default:
+			return nil, notFound(path[:depth+1])
+		}
+	}
+	return node, nil
+}
+
+// edit replaces the container the path ends in with what fn makes of it and
+// the path's last token, and returns the new root.
+func edit(node any, path []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+
+	switch c := node.(type) {
+	case map[string]any:
+		child, ok := c[path[0]]
+		if !ok {
+			return nil, notFound(path[:1])
+		}
+		child, err := edit(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[path[0]] = child
+		return c, nil
+	case []any:
+		i, err := arrayIndex(path[0], len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := edit(c[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = child
+		return c, nil
+	}
+	return nil, notFound(path[:1])
+}
+
+func add(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return edit(root, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[token] = value
+			return c, nil
+		case []any:
+			i, err := arrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		return nil, notFound(path)
+	})
+}
+
+// remove returns the new root and the value it removed.
+func remove(root any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	var removed any
+	root, err := edit(root, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			value, ok := c[token]
+			if !ok {
+				return nil, notFound(path)
+			}
+			removed = value
+			delete(c, token)
+			return c, nil
+		case []any:
+			i, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = c[i]
+			return append(c[:i], c[i+1:]...), nil
+		}
+		return nil, notFound(path)
+	})
+	return root, removed, err
+}
+
+func clone(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+// equal compares two decoded JSON values the way the test operation does:
+// numbers by value, objects regardless of member order.
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		m, okX := new(big.Float).SetString(x.String())
+		n, okY := new(big.Float).SetString(y.String())
+		return okX && okY && m.Cmp(n) == 0
+	}
+	return a == b
+}
@@ -0,0 +1,122 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// sameJSON compares two documents regardless of member order.
+func sameJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	g, err := decode(got)
+	if err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+	w, err := decode([]byte(want))
+	if err != nil {
+		t.Fatalf("invalid expectation %s: %v", want, err)
+	}
+	if !equal(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// From RFC 7396, appendix A
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("Merge(%s, %s): %v", tt.doc, tt.patch, err)
+			continue
+		}
+		sameJSON(t, got, tt.want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	doc := `{"title":"Report","completed":false,"project_id":null,"list":[1,2,3],"a/b":{"~c":1}}`
+
+	tests := []struct {
+		name, patch, want string
+	}{
+		{"replace", `[{"op":"replace","path":"/title","value":"Weekly report"}]`,
+			`{"title":"Weekly report","completed":false,"project_id":null,"list":[1,2,3],"a/b":{"~c":1}}`},
+		{"add member and array element", `[{"op":"add","path":"/x","value":true},{"op":"add","path":"/list/1","value":9},{"op":"add","path":"/list/-","value":4}]`,
+			`{"title":"Report","completed":false,"project_id":null,"list":[1,9,2,3,4],"a/b":{"~c":1},"x":true}`},
+		{"remove", `[{"op":"remove","path":"/list/0"},{"op":"remove","path":"/a~1b/~0c"}]`,
+			`{"title":"Report","completed":false,"project_id":null,"list":[2,3],"a/b":{}}`},
+		{"move and copy", `[{"op":"move","from":"/title","path":"/name"},{"op":"copy","from":"/list/2","path":"/project_id"}]`,
+			`{"name":"Report","completed":false,"project_id":3,"list":[1,2,3],"a/b":{"~c":1}}`},
+		{"test then replace", `[{"op":"test","path":"/project_id","value":null},{"op":"test","path":"/list","value":[1,2,3.0]},{"op":"replace","path":"/completed","value":true}]`,
+			`{"title":"Report","completed":true,"project_id":null,"list":[1,2,3],"a/b":{"~c":1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	doc := `{"title":"Report","list":[1]}`
+
+	tests := []struct {
+		name, patch string
+		want        error
+	}{
+		{"failed test", `[{"op":"replace","path":"/title","value":"x"},{"op":"test","path":"/title","value":"Report"}]`, ErrTestFailed},
+		{"missing member", `[{"op":"replace","path":"/nope","value":1}]`, ErrInvalidPatch},
+		{"index out of range", `[{"op":"add","path":"/list/5","value":1}]`, ErrInvalidPatch},
+		{"leading zero index", `[{"op":"remove","path":"/list/00"}]`, ErrInvalidPatch},
+		{"missing value", `[{"op":"add","path":"/x"}]`, ErrInvalidPatch},
+		{"unknown op", `[{"op":"increment","path":"/list/0"}]`, ErrInvalidPatch},
+		{"not an array", `{"op":"remove","path":"/title"}`, ErrInvalidPatch},
+		{"bad pointer", `[{"op":"remove","path":"title"}]`, ErrInvalidPatch},
+		{"move into itself", `[{"op":"move","from":"/list","path":"/list/0"}]`, ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Apply([]byte(doc), []byte(tt.patch)); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperationValueNull(t *testing.T) {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[{"op":"replace","path":"/a","value":null}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if ops[0].Value == nil {
+		t.Fatal("a null value must be kept apart from a missing one")
+	}
+
+	got, err := Apply([]byte(`{"a":1}`), []byte(`[{"op":"replace","path":"/a","value":null}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameJSON(t, got, `{"a":null}`)
+}
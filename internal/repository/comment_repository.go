@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+type CommentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCommentRepository(db *pgxpool.Pool) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+const commentColumns = `
+	c.id, c.todo_id, c.user_id, u.name, c.body, c.edited_at, c.deleted_at, c.created_at, c.updated_at
+`
+
+func scanComment(row pgx.Row, c *models.Comment) error {
+	err := row.Scan(
+		&c.ID,
+		&c.TodoID,
+		&c.UserID,
+		&c.Author.Name,
+		&c.Body,
+		&c.EditedAt,
+		&c.DeletedAt,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	c.Author.ID = c.UserID
+	c.Edited = c.EditedAt != nil
+	c.Deleted = c.DeletedAt != nil
+	return nil
+}
+
+func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	// Join the author back in so the response matches what listings return
+	query := `
+		WITH inserted AS (
+			INSERT INTO todo_comments (todo_id, user_id, body)
+			VALUES ($1, $2, $3)
+			RETURNING id, user_id, created_at, updated_at
+		)
+		SELECT i.id, u.name, i.created_at, i.updated_at
+		FROM inserted i
+		JOIN users u ON u.id = i.user_id
+	`
+
+	return withUserScope(ctx, r.db, comment.UserID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, comment.TodoID, comment.UserID, comment.Body).
+			Scan(&comment.ID, &comment.Author.Name, &comment.CreatedAt, &comment.UpdatedAt)
+	})
+}
+
+// GetByID returns the comment if userID can see the todo it belongs to.
+func (r *CommentRepository) GetByID(ctx context.Context, id, userID int) (*models.Comment, error) {
+	query := `SELECT ` + commentColumns + `
+		FROM todo_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1
+	`
+
+	comment := &models.Comment{}
+	err := withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return scanComment(tx.QueryRow(ctx, query, id), comment)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("comment not found")
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// ListByTodoID returns a page of a todo's comments in the order they were posted.
+// Deleted comments are kept in the thread as markers with an empty body.
+func (r *CommentRepository) ListByTodoID(ctx context.Context, todoID, userID, page, limit int) ([]models.Comment, int, error) {
+	query := `SELECT ` + commentColumns + `
+		FROM todo_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.todo_id = $1
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT $2 OFFSET $3
+	`
+
+	var total int
+	comments := []models.Comment{}
+	err := withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM todo_comments WHERE todo_id = $1`, todoID).Scan(&total)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, todoID, limit, (page-1)*limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var comment models.Comment
+			if err := scanComment(rows, &comment); err != nil {
+				return err
+			}
+			comments = append(comments, comment)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+func (r *CommentRepository) Update(ctx context.Context, comment *models.Comment) error {
+	query := `
+		UPDATE todo_comments
+		SET body = $1, edited_at = NOW()
+		WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL
+		RETURNING edited_at, updated_at
+	`
+
+	err := withUserScope(ctx, r.db, comment.UserID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, comment.Body, comment.ID, comment.UserID).
+			Scan(&comment.EditedAt, &comment.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("comment not found or unauthorized")
+		}
+		return err
+	}
+
+	comment.Edited = true
+	return nil
+}
+
+// Delete marks a comment as deleted and clears its body.
+// The row is kept so the thread still shows that something was there.
+func (r *CommentRepository) Delete(ctx context.Context, id, userID int) error {
+	query := `
+		UPDATE todo_comments
+		SET body = '', deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return errors.New("comment not found")
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+var (
+	// ErrIdempotencyKeyReused refuses a key that was used for a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+	// ErrIdempotencyInFlight means the first request with the key is still running
+	ErrIdempotencyInFlight = errors.New("a request with this idempotency key is in progress")
+)
+
+// idempotencyLockTimeout is how long a request may hold its key without
+// finishing before a retry takes the key over. It is well above the server's
+// write timeout, so only requests that died (with the server) run into it.
+const idempotencyLockTimeout = time.Minute
+
+type IdempotencyRepository struct {
+	db *pgxpool.Pool
+	// systemDB may bypass row-level security; only withSystemScope uses it
+	systemDB *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(db, systemDB *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db, systemDB: systemDB}
+}
+
+// Begin claims key for a request of userID with the given fingerprint. The
+// returned record has a nil Status if the caller got the key and runs the
+// request (then finishing with Complete or Release); otherwise it holds the
+// stored response to replay. Two requests racing for a new key are serialized
+// by the unique key: one gets it, the other ErrIdempotencyInFlight. Expired
+// keys, and keys of requests that never finished, are taken over.
+func (r *IdempotencyRepository) Begin(ctx context.Context, userID int, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, error) {
+	claimQuery := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status = NULL, headers = NULL, body = NULL,
+		    locked_at = NOW(), expires_at = EXCLUDED.expires_at, created_at = NOW()
+		WHERE idempotency_keys.expires_at < NOW()
+		   OR (idempotency_keys.status IS NULL AND idempotency_keys.fingerprint = EXCLUDED.fingerprint
+		       AND idempotency_keys.locked_at < NOW() - make_interval(secs => $5))
+		RETURNING id
+	`
+
+	existingQuery := `
+		SELECT id, fingerprint, status, headers, body
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`
+
+	var result *models.IdempotencyRecord
+	err := withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		record := &models.IdempotencyRecord{UserID: userID, Key: key, Fingerprint: fingerprint}
+
+		// The existing key can be purged between the two queries; then claim it again
+		for attempt := 0; attempt < 2; attempt++ {
+			err := tx.QueryRow(ctx, claimQuery, userID, key, fingerprint, ttl.Seconds(), idempotencyLockTimeout.Seconds()).
+				Scan(&record.ID)
+			if err == nil {
+				result = record
+				return nil
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+
+			var stored models.IdempotencyRecord
+			var headers map[string][]string
+			err = tx.QueryRow(ctx, existingQuery, userID, key).
+				Scan(&stored.ID, &stored.Fingerprint, &stored.Status, &headers, &stored.Body)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if stored.Fingerprint != fingerprint {
+				return ErrIdempotencyKeyReused
+			}
+			if stored.Status == nil {
+				return ErrIdempotencyInFlight
+			}
+			stored.UserID, stored.Key, stored.Headers = userID, key, http.Header(headers)
+			result = &stored
+			return nil
+		}
+
+		return ErrIdempotencyInFlight
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Complete stores the response of the request of userID that claimed the key.
+func (r *IdempotencyRepository) Complete(ctx context.Context, id int64, userID, status int, headers http.Header, body []byte) error {
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			UPDATE idempotency_keys SET status = $3, headers = $4, body = $5
+			WHERE id = $1 AND user_id = $2 AND status IS NULL
+		`, id, userID, status, map[string][]string(headers), body)
+		return err
+	})
+}
+
+// Release gives up the key of a request of userID that didn't finish with a
+// response worth replaying, so a retry runs it again.
+func (r *IdempotencyRepository) Release(ctx context.Context, id int64, userID int) error {
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM idempotency_keys WHERE id = $1 AND user_id = $2 AND status IS NULL`, id, userID)
+		return err
+	})
+}
+
+// PurgeExpired deletes expired keys and reports how many there were. It runs
+// as a background job.
+func (r *IdempotencyRepository) PurgeExpired(ctx context.Context) (int, error) {
+	var purged int
+	err := withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+		purged = int(result.RowsAffected())
+		return err
+	})
+	return purged, err
+}
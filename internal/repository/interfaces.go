@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"net/http"
+	"time"
 
 	"github.com/pigeio/todo-api/internal/models"
 )
@@ -16,8 +18,126 @@ type User_Repository interface {
 // TodoRepository defines the interface for todo-related database operations
 type Todo_Repository interface {
 	Create(ctx context.Context, todo *models.Todo) error
-	GetByID(ctx context.Context, id int) (*models.Todo, error)
-	GetByUserID(ctx context.Context, userID, page, limit int, status, sortBy string) ([]models.Todo, int, error)
-	Update(ctx context.Context, todo *models.Todo) error
+	GetByID(ctx context.Context, id, userID int) (*models.Todo, error)
+	GetByIDs(ctx context.Context, ids []int, userID int) (map[int]*models.Todo, error)
+	GetByUserID(ctx context.Context, userID int, opts models.TodoListOptions) (*models.TodoPage, error)
+	CountByUserID(ctx context.Context, userID int, opts []models.TodoListOptions) ([]int, error)
+	FindSimilar(ctx context.Context, todo *models.Todo, userID, limit int) ([]models.SimilarTodo, error)
+	FindDuplicates(ctx context.Context, title string, userID int) ([]models.SimilarTodo, error)
+	CanBeAssigned(ctx context.Context, todo *models.Todo, assigneeID, actorID int) (bool, error)
+	Update(ctx context.Context, todo *models.Todo, userID int) error
+	Delete(ctx context.Context, todo *models.Todo, userID int) error
+	Move(ctx context.Context, todo *models.Todo, anchorID int, after bool, userID int) error
+	ApplyBulk(ctx context.Context, userID int, writes []models.BulkWrite, atomic bool) ([]error, error)
+	AddDependency(ctx context.Context, todoID, blockerID, userID int) error
+	RemoveDependency(ctx context.Context, todoID, blockerID, userID int) error
+	GetDependencies(ctx context.Context, todoID, userID int) (*models.TodoDependencies, error)
+	ListTrash(ctx context.Context, userID, page, limit int) ([]models.Todo, int, error)
+	Restore(ctx context.Context, id, userID int) error
+	EmptyTrash(ctx context.Context, userID int) (int, error)
+	SetArchived(ctx context.Context, todo *models.Todo, archived bool, userID int) error
+	ArchiveCompleted(ctx context.Context, userID int, before time.Time) (int, error)
+	ListRevisions(ctx context.Context, todoID, userID, page, limit int) ([]models.TodoRevision, int, error)
+	GetRevision(ctx context.Context, todoID, revision, userID int) (*models.TodoRevision, error)
+	ListActivity(ctx context.Context, userID, page, limit int) ([]models.TodoRevision, int, error)
+	CheckSyncToken(ctx context.Context, token string, notBefore time.Time) error
+	SyncChanges(ctx context.Context, token string, notBefore time.Time, limit, userID int) (*models.SyncPage, error)
+	FieldChangesSince(ctx context.Context, bases map[int]int, userID int) (map[int][]models.TodoFieldChange, error)
+}
+
+// Comment_Repository defines the interface for todo comment database operations
+type Comment_Repository interface {
+	Create(ctx context.Context, comment *models.Comment) error
+	GetByID(ctx context.Context, id, userID int) (*models.Comment, error)
+	ListByTodoID(ctx context.Context, todoID, userID, page, limit int) ([]models.Comment, int, error)
+	Update(ctx context.Context, comment *models.Comment) error
+	Delete(ctx context.Context, id, userID int) error
+}
+
+// Project_Repository defines the interface for project database operations
+type Project_Repository interface {
+	Create(ctx context.Context, project *models.Project) error
+	GetByID(ctx context.Context, id, userID int) (*models.Project, error)
+	GetByUserID(ctx context.Context, userID int) ([]models.Project, error)
+	Update(ctx context.Context, project *models.Project, userID int) error
+	Delete(ctx context.Context, id, userID int) error
+}
+
+// Share_Repository defines the interface for sharing and invitation database operations
+type Share_Repository interface {
+	Invite(ctx context.Context, share *models.Share) error
+	GetByID(ctx context.Context, id int) (*models.Share, error)
+	ListByResource(ctx context.Context, resourceType string, resourceID int) ([]models.Share, error)
+	ListPendingForUser(ctx context.Context, userID int) ([]models.Share, error)
+	Respond(ctx context.Context, id, userID int, accept bool) (*models.Share, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// Notification_Repository defines the interface for notification database operations
+type Notification_Repository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	GetByUserID(ctx context.Context, userID, page, limit int, unreadOnly bool) ([]models.Notification, int, error)
+	MarkRead(ctx context.Context, id, userID int) error
+}
+
+// ShareLink_Repository defines the interface for public share link database operations
+type ShareLink_Repository interface {
+	Create(ctx context.Context, link *models.ShareLink) error
+	GetActiveByTokenHash(ctx context.Context, tokenHash string) (*models.ShareLink, error)
+	GetByUserID(ctx context.Context, userID int) ([]models.ShareLink, error)
+	Revoke(ctx context.Context, id, userID int) error
+	RecordAccess(ctx context.Context, id int) error
+	GetView(ctx context.Context, link *models.ShareLink) (*models.SharedView, error)
+}
+
+// Workspace_Repository defines the interface for workspace and membership database operations
+type Workspace_Repository interface {
+	Create(ctx context.Context, workspace *models.Workspace, ownerID int) error
+	GetByUserID(ctx context.Context, userID int) ([]models.Workspace, error)
+	GetPersonalID(ctx context.Context, userID int) (int, error)
+	GetMember(ctx context.Context, workspaceID, userID int) (*models.WorkspaceMember, error)
+	ListMembers(ctx context.Context, workspaceID int) ([]models.WorkspaceMember, error)
+	RemoveMember(ctx context.Context, workspaceID, userID int) error
+	TransferOwnership(ctx context.Context, workspaceID, fromUserID, toUserID int) error
+	Invite(ctx context.Context, inv *models.WorkspaceInvitation) error
+	ListPendingInvitations(ctx context.Context, email string) ([]models.WorkspaceInvitation, error)
+	RespondToInvitation(ctx context.Context, id, userID int, email string, accept bool) (*models.WorkspaceInvitation, error)
+}
+
+// Settings_Repository defines the interface for per-user preferences
+type Settings_Repository interface {
+	Get(ctx context.Context, userID int) (*models.UserSettings, error)
+	Update(ctx context.Context, userID int, settings *models.UserSettings) error
+}
+
+// SavedView_Repository defines the interface for saved todo views
+type SavedView_Repository interface {
+	Create(ctx context.Context, view *models.SavedView) error
+	GetByID(ctx context.Context, id, userID int) (*models.SavedView, error)
+	GetByUserID(ctx context.Context, userID int) ([]models.SavedView, error)
+	Update(ctx context.Context, view *models.SavedView) error
 	Delete(ctx context.Context, id, userID int) error
 }
+
+// WorkflowState_Repository defines the interface for per-project workflow states
+type WorkflowState_Repository interface {
+	ListByProject(ctx context.Context, projectID, userID int) ([]models.WorkflowState, error)
+	GetByID(ctx context.Context, id, userID int) (*models.WorkflowState, error)
+	Create(ctx context.Context, state *models.WorkflowState, userID int) error
+	Update(ctx context.Context, state *models.WorkflowState, userID int) error
+	Delete(ctx context.Context, state *models.WorkflowState, moveTo *int, userID int) error
+	ListTransitions(ctx context.Context, todoID, userID int) ([]models.StateTransition, error)
+}
+
+// Undo_Repository defines the interface for undo tokens
+type Undo_Repository interface {
+	Create(ctx context.Context, undo *models.Undo) error
+	Redeem(ctx context.Context, tokenHash string, userID int) (restored, skipped []int, err error)
+}
+
+// Idempotency_Repository defines the interface for stored Idempotency-Key responses
+type Idempotency_Repository interface {
+	Begin(ctx context.Context, userID int, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, id int64, userID, status int, headers http.Header, body []byte) error
+	Release(ctx context.Context, id int64, userID int) error
+}
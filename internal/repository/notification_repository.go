@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create runs in the scope of the notification's actor, the only user besides
+// the recipient row-level security lets write it, so ActorID must be set.
+func (r *NotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	if notification.ActorID == nil {
+		return errors.New("notification has no actor")
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, type, todo_id, actor_id, message)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	return withUserScope(ctx, r.db, *notification.ActorID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query,
+			notification.UserID,
+			notification.Type,
+			notification.TodoID,
+			notification.ActorID,
+			notification.Message,
+		).Scan(&notification.ID, &notification.CreatedAt)
+	})
+}
+
+// GetByUserID returns a page of the user's notifications, newest first.
+func (r *NotificationRepository) GetByUserID(ctx context.Context, userID, page, limit int, unreadOnly bool) ([]models.Notification, int, error) {
+	where := "WHERE user_id = $1"
+	if unreadOnly {
+		where += " AND read_at IS NULL"
+	}
+
+	query := `
+		SELECT id, user_id, type, todo_id, actor_id, message, read_at, created_at
+		FROM notifications ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	var total int
+	notifications := []models.Notification{}
+	err := withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM notifications "+where, userID).Scan(&total); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, userID, limit, (page-1)*limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var n models.Notification
+			err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.TodoID, &n.ActorID, &n.Message, &n.ReadAt, &n.CreatedAt)
+			if err != nil {
+				return err
+			}
+			n.Read = n.ReadAt != nil
+			notifications = append(notifications, n)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+func (r *NotificationRepository) MarkRead(ctx context.Context, id, userID int) error {
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, id, userID)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return errors.New("notification not found")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+)
+
+// Manual todo order uses fractional indexing: every todo has a position key,
+// todos sort by key (byte order, COLLATE "C"), and a todo is moved by giving
+// it a key between its new neighbours, so a move touches a single row.
+//
+// A key is an integer part followed by an optional fraction, both written
+// with positionDigits. The integer part's first character says how many
+// digits follow ('a' one, 'b' two, ... and 'Z', 'Y', ... for negative
+// values), so appending or prepending keeps keys short; the fraction only
+// grows when inserting between close neighbours. See
+// https://observablehq.com/@dgreensp/implementing-fractional-indexing
+const positionDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// maxPositionLength is how long keys may grow before the todos of a workspace
+// get fresh, evenly spaced ones (see RebalancePositions).
+const maxPositionLength = 24
+
+var errInvalidPosition = errors.New("invalid position key")
+
+// smallestInteger can't be decremented; keys before it go into its fraction.
+var smallestInteger = "A" + strings.Repeat(positionDigits[:1], 26)
+
+func integerLength(head byte) (int, error) {
+	switch {
+	case head >= 'a' && head <= 'z':
+		return int(head-'a') + 2, nil
+	case head >= 'A' && head <= 'Z':
+		return int('Z'-head) + 2, nil
+	}
+	return 0, errInvalidPosition
+}
+
+// splitPosition separates a key into its integer part and fraction.
+func splitPosition(key string) (string, string, error) {
+	if key == "" {
+		return "", "", errInvalidPosition
+	}
+	n, err := integerLength(key[0])
+	if err != nil || n > len(key) {
+		return "", "", errInvalidPosition
+	}
+	fraction := key[n:]
+	if strings.HasSuffix(fraction, positionDigits[:1]) {
+		return "", "", errInvalidPosition
+	}
+	return key[:n], fraction, nil
+}
+
+// positionBetween returns a key that sorts strictly between a and b. An empty
+// a means "before b", an empty b "after a", and both empty the first key.
+func positionBetween(a, b string) (string, error) {
+	if a != "" && b != "" && a >= b {
+		return "", errInvalidPosition
+	}
+
+	switch {
+	case a == "" && b == "":
+		return "a" + positionDigits[:1], nil
+
+	case a == "":
+		intB, fracB, err := splitPosition(b)
+		if err != nil {
+			return "", err
+		}
+		if intB == smallestInteger {
+			return intB + fractionBetween("", fracB), nil
+		}
+		if intB < b {
+			return intB, nil
+		}
+		return decrementInteger(intB)
+
+	case b == "":
+		intA, fracA, err := splitPosition(a)
+		if err != nil {
+			return "", err
+		}
+		next, err := incrementInteger(intA)
+		if err != nil {
+			// Out of integers: grow the fraction instead
+			return intA + fractionBetween(fracA, ""), nil
+		}
+		return next, nil
+	}
+
+	intA, fracA, err := splitPosition(a)
+	if err != nil {
+		return "", err
+	}
+	intB, fracB, err := splitPosition(b)
+	if err != nil {
+		return "", err
+	}
+	if intA == intB {
+		return intA + fractionBetween(fracA, fracB), nil
+	}
+	next, err := incrementInteger(intA)
+	if err != nil {
+		return "", err
+	}
+	if next < b {
+		return next, nil
+	}
+	return intA + fractionBetween(fracA, ""), nil
+}
+
+// fractionBetween returns a fraction strictly between a and b (b empty meaning 1).
+// Neither may end in the zero digit, and neither does the result.
+func fractionBetween(a, b string) string {
+	zero := positionDigits[0]
+
+	if b != "" {
+		// Keep the common prefix, with a padded with zeros
+		n := 0
+		for n < len(b) {
+			c := zero
+			if n < len(a) {
+				c = a[n]
+			}
+			if c != b[n] {
+				break
+			}
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + fractionBetween(rest, b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(positionDigits, a[0])
+	}
+	digitB := len(positionDigits)
+	if b != "" {
+		digitB = strings.IndexByte(positionDigits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(positionDigits[(digitA+digitB+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if a != "" {
+		rest = a[1:]
+	}
+	return string(positionDigits[digitA]) + fractionBetween(rest, "")
+}
+
+func incrementInteger(x string) (string, error) {
+	head, digits := x[0], []byte(x[1:])
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := strings.IndexByte(positionDigits, digits[i]) + 1
+		if d < len(positionDigits) {
+			digits[i] = positionDigits[d]
+			return string(head) + string(digits), nil
+		}
+		digits[i] = positionDigits[0]
+	}
+
+	// Carried out of the last digit: move to the next length
+	switch head {
+	case 'Z':
+		return "a" + positionDigits[:1], nil
+	case 'z':
+		return "", errInvalidPosition
+	}
+	head++
+	if head > 'a' {
+		digits = append(digits, positionDigits[0])
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), nil
+}
+
+func decrementInteger(x string) (string, error) {
+	last := positionDigits[len(positionDigits)-1]
+	head, digits := x[0], []byte(x[1:])
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := strings.IndexByte(positionDigits, digits[i]) - 1
+		if d >= 0 {
+			digits[i] = positionDigits[d]
+			return string(head) + string(digits), nil
+		}
+		digits[i] = last
+	}
+
+	// Borrowed past the first digit: move to the previous length
+	switch head {
+	case 'a':
+		return "Z" + string(last), nil
+	case 'A':
+		return "", errInvalidPosition
+	}
+	head--
+	if head < 'Z' {
+		digits = append(digits, last)
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), nil
+}
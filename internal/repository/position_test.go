@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestPositionBetween(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"", "", "a0"},
+		{"a0", "", "a1"},
+		{"az", "", "b00"},
+		{"", "a0", "Zz"},
+		{"", "a1", "a0"},
+		{"Zz", "", "a0"},
+		{"a0", "a1", "a0V"},
+		{"a0V", "a1", "a0l"},
+		{"a0", "a0V", "a0G"},
+		{"a1", "a2", "a1V"},
+		{"b00", "b01", "b00V"},
+	}
+
+	for _, tt := range tests {
+		got, err := positionBetween(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("positionBetween(%q, %q) error: %v", tt.a, tt.b, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("positionBetween(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"a1", "a0"}, {"a1", "a1"}, {"a10", ""}, {"?", ""}} {
+		if _, err := positionBetween(bad[0], bad[1]); err == nil {
+			t.Errorf("positionBetween(%q, %q): want error", bad[0], bad[1])
+		}
+	}
+}
+
+// Random inserts must always land strictly between their neighbours.
+func TestPositionBetweenKeepsOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := []string{}
+
+	for i := 0; i < 2000; i++ {
+		at := rng.Intn(len(keys) + 1)
+		var a, b string
+		if at > 0 {
+			a = keys[at-1]
+		}
+		if at < len(keys) {
+			b = keys[at]
+		}
+
+		key, err := positionBetween(a, b)
+		if err != nil {
+			t.Fatalf("positionBetween(%q, %q): %v", a, b, err)
+		}
+		if (a != "" && key <= a) || (b != "" && key >= b) {
+			t.Fatalf("positionBetween(%q, %q) = %q is out of order", a, b, key)
+		}
+
+		keys = append(keys[:at], append([]string{key}, keys[at:]...)...)
+	}
+
+	if !sort.StringsAreSorted(keys) {
+		t.Error("keys are not sorted")
+	}
+
+	// Appending stays short
+	last := ""
+	for i := 0; i < 10000; i++ {
+		last, _ = positionBetween(last, "")
+	}
+	if len(last) > 4 {
+		t.Errorf("after 10000 appends the key is %q", last)
+	}
+}
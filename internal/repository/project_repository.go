@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+type ProjectRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProjectRepository(db *pgxpool.Pool) *ProjectRepository {
+	return &ProjectRepository{db: db}
+}
+
+// Like todos, projects are always read within the active workspace.
+const projectColumns = `p.id, p.workspace_id, p.user_id, p.name, p.created_at, p.updated_at, a.role_rank`
+
+func scanProject(row pgx.Row, project *models.Project) error {
+	var roleRank int
+	err := row.Scan(
+		&project.ID,
+		&project.WorkspaceID,
+		&project.UserID,
+		&project.Name,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+		&roleRank,
+	)
+	if err != nil {
+		return err
+	}
+
+	project.Role = models.RoleFromRank(roleRank)
+	return nil
+}
+
+func (r *ProjectRepository) Create(ctx context.Context, project *models.Project) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO projects (workspace_id, user_id, name)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`
+
+	project.WorkspaceID = workspaceID
+	return withUserScope(ctx, r.db, project.UserID, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, workspaceID, project.UserID, project.Name).
+			Scan(&project.ID, &project.CreatedAt, &project.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		// Every project starts out with the default workflow
+		for _, state := range models.DefaultWorkflowStates {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO workflow_states (project_id, name, position, is_done)
+				VALUES ($1, $2, $3, $4)
+			`, project.ID, state.Name, state.Position, state.IsDone)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetByID returns the project only if userID has some role on it, and reports that role.
+func (r *ProjectRepository) GetByID(ctx context.Context, id, userID int) (*models.Project, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + projectColumns + `
+		FROM projects p
+		JOIN project_access a ON a.project_id = p.id AND a.user_id = $2
+		WHERE p.id = $1 AND p.workspace_id = $3
+	`
+
+	project := &models.Project{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return scanProject(tx.QueryRow(ctx, query, id, userID, workspaceID), project)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("project not found")
+		}
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// GetByUserID lists every project the user owns or has been given access to.
+func (r *ProjectRepository) GetByUserID(ctx context.Context, userID int) ([]models.Project, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + projectColumns + `
+		FROM projects p
+		JOIN project_access a ON a.project_id = p.id AND a.user_id = $1
+		WHERE p.workspace_id = $2
+		ORDER BY p.name ASC, p.id ASC
+	`
+
+	projects := []models.Project{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, userID, workspaceID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var project models.Project
+			if err := scanProject(rows, &project); err != nil {
+				return err
+			}
+			projects = append(projects, project)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// Update renames the project if userID is at least an editor on it.
+func (r *ProjectRepository) Update(ctx context.Context, project *models.Project, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE projects p
+		SET name = $1
+		WHERE p.id = $2 AND p.workspace_id = $5 AND EXISTS (
+			SELECT 1 FROM project_access a
+			WHERE a.project_id = p.id AND a.user_id = $3 AND a.role_rank >= $4
+		)
+		RETURNING updated_at
+	`
+
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, project.Name, project.ID, userID, models.RoleRank(models.RoleEditor), workspaceID).
+			Scan(&project.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("project not found or unauthorized")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes the project if userID is an owner of it. Its todos are kept
+// and simply leave the project.
+func (r *ProjectRepository) Delete(ctx context.Context, id, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM projects p
+		WHERE p.id = $1 AND p.workspace_id = $4 AND EXISTS (
+			SELECT 1 FROM project_access a
+			WHERE a.project_id = p.id AND a.user_id = $2 AND a.role_rank >= $3
+		)
+	`
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, id, userID, models.RoleRank(models.RoleOwner), workspaceID)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return errors.New("project not found or unauthorized")
+		}
+
+		return nil
+	})
+}
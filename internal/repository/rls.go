@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Row-level security (see migrations/000007 and 000023) only lets a
+// transaction see the rows of the user named in the app.user_id setting.
+// Repositories run every query on an RLS-protected table (todos, projects and
+// everything hanging off them, notifications, saved views, undo tokens,
+// idempotency keys) through
+// withUserScope, so a query that forgets its own user or workspace predicate
+// still cannot read or change someone else's data.
+//
+// The settings are set with is_local = true, so they end with the transaction
+// and never leak to the next user of the pooled connection.
+
+// withUserScope acquires a connection from the pool, opens a transaction with
+// app.user_id set to userID and runs fn in it. The transaction is committed if
+// fn succeeds and rolled back otherwise.
+func withUserScope(ctx context.Context, db *pgxpool.Pool, userID int, fn func(tx pgx.Tx) error) error {
+	return withSettings(ctx, db, map[string]string{"app.user_id": strconv.Itoa(userID)}, fn)
+}
+
+// withSystemScope runs fn on systemDB, whose role may bypass RLS (see
+// migrations/000022); nothing on the pool requests use can. It is meant for
+// background jobs and administrative operations that act across users, never
+// for anything driven directly by a request's input.
+func withSystemScope(ctx context.Context, systemDB *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	return withSettings(ctx, systemDB, nil, fn)
+}
+
+// withSystemScopeAs is withSystemScope for administrative operations a user
+// triggers: RLS is bypassed, but app.user_id still names the user, so triggers
+// that record who made a change (see migrations/000013) can.
+func withSystemScopeAs(ctx context.Context, systemDB *pgxpool.Pool, userID int, fn func(tx pgx.Tx) error) error {
+	return withSettings(ctx, systemDB, map[string]string{"app.user_id": strconv.Itoa(userID)}, fn)
+}
+
+func withSettings(ctx context.Context, db *pgxpool.Pool, settings map[string]string, fn func(tx pgx.Tx) error) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for name, value := range settings {
+		if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", name, value); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,422 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+// These tests need a real Postgres. Point TEST_DATABASE_URL at a database the
+// tests may create schemas and roles in; they are skipped otherwise.
+
+// setupRLSTestDB applies every migration to a fresh schema and returns a pool whose
+// connections use that schema, and a system pool that may bypass RLS. The test
+// login may be a superuser, which bypasses RLS, so neither pool uses it as is:
+// the first switches to an ordinary role and the system pool to one that is a
+// member of todo_api_system. Both roles are dropped with the schema.
+func setupRLSTestDB(t *testing.T) (*pgxpool.Pool, *pgxpool.Pool, *pgx.Conn) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping row-level security tests")
+	}
+
+	ctx := context.Background()
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	schema := fmt.Sprintf("rls_test_%d", time.Now().UnixNano())
+	appRole, systemRole := schema+"_app", schema+"_system"
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		for _, role := range []string{appRole, systemRole} {
+			admin.Exec(ctx, "DROP OWNED BY "+role)
+			admin.Exec(ctx, "DROP ROLE IF EXISTS "+role)
+		}
+		admin.Close(ctx)
+	})
+
+	if _, err := admin.Exec(ctx, "SET search_path TO "+schema+", public"); err != nil {
+		t.Fatalf("set search_path: %v", err)
+	}
+
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		if _, err := admin.Exec(ctx, string(sql)); err != nil {
+			t.Fatalf("apply %s: %v", filepath.Base(file), err)
+		}
+	}
+
+	// The test login is made a member of both roles so it can SET ROLE to them
+	setup := fmt.Sprintf(`
+		CREATE ROLE %[1]s NOLOGIN;
+		CREATE ROLE %[2]s NOLOGIN;
+		GRANT todo_api_system TO %[2]s;
+		GRANT %[1]s, %[2]s TO CURRENT_USER;
+		GRANT USAGE ON SCHEMA %[3]s TO %[1]s, %[2]s;
+		GRANT ALL ON ALL TABLES IN SCHEMA %[3]s TO %[1]s, %[2]s;
+		GRANT ALL ON ALL SEQUENCES IN SCHEMA %[3]s TO %[1]s, %[2]s;
+		GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA %[3]s TO %[1]s, %[2]s;
+	`, appRole, systemRole, schema)
+	if _, err := admin.Exec(ctx, setup); err != nil {
+		t.Fatalf("create test roles: %v", err)
+	}
+
+	newPool := func(role string) *pgxpool.Pool {
+		config, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			t.Fatalf("parse config: %v", err)
+		}
+		config.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+		config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, "SET ROLE "+role)
+			return err
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, config)
+		if err != nil {
+			t.Fatalf("create pool for %s: %v", role, err)
+		}
+		t.Cleanup(pool.Close)
+		return pool
+	}
+
+	return newPool(appRole), newPool(systemRole), admin
+}
+
+// rlsFixture is two users, each in their own personal workspace, and one todo owned by alice.
+type rlsFixture struct {
+	pool       *pgxpool.Pool
+	systemPool *pgxpool.Pool
+	admin      *pgx.Conn
+	alice      *models.User
+	bob        *models.User
+	aliceCtx   context.Context
+	todo       *models.Todo
+}
+
+func newRLSFixture(t *testing.T) *rlsFixture {
+	t.Helper()
+	pool, systemPool, admin := setupRLSTestDB(t)
+	ctx := context.Background()
+
+	users := NewUserRepository(pool)
+	alice := &models.User{Name: "Alice", Email: "alice@example.com", Password: "x"}
+	bob := &models.User{Name: "Bob", Email: "bob@example.com", Password: "x"}
+	for _, u := range []*models.User{alice, bob} {
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("create user %s: %v", u.Email, err)
+		}
+	}
+
+	workspaceID, err := NewWorkspaceRepository(pool, systemPool).GetPersonalID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("personal workspace: %v", err)
+	}
+	aliceCtx := tenant.WithWorkspace(ctx, tenant.Workspace{ID: workspaceID, Role: models.WorkspaceRoleOwner})
+
+	todo := &models.Todo{UserID: alice.ID, Title: "Alice's secret plan", Description: "tell no one"}
+	if err := NewTodoRepository(pool, systemPool).Create(aliceCtx, todo); err != nil {
+		t.Fatalf("create todo: %v", err)
+	}
+
+	return &rlsFixture{pool: pool, systemPool: systemPool, admin: admin, alice: alice, bob: bob, aliceCtx: aliceCtx, todo: todo}
+}
+
+func TestRLSHidesOtherUsersTodosFromUnfilteredQueries(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+
+	// A query that "forgets" every ownership predicate
+	err := withUserScope(ctx, f.pool, f.bob.ID, func(tx pgx.Tx) error {
+		var title string
+		return tx.QueryRow(ctx, `SELECT title FROM todos WHERE id = $1`, f.todo.ID).Scan(&title)
+	})
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("bob read alice's todo: got err %v, want pgx.ErrNoRows", err)
+	}
+
+	var bobCount, aliceCount int
+	withUserScope(ctx, f.pool, f.bob.ID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, `SELECT COUNT(*) FROM todos`).Scan(&bobCount)
+	})
+	withUserScope(ctx, f.pool, f.alice.ID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, `SELECT COUNT(*) FROM todos`).Scan(&aliceCount)
+	})
+	if bobCount != 0 {
+		t.Errorf("bob sees %d todos, want 0", bobCount)
+	}
+	if aliceCount != 1 {
+		t.Errorf("alice sees %d todos, want 1", aliceCount)
+	}
+}
+
+func TestRLSFailsClosedWithoutUserScope(t *testing.T) {
+	f := newRLSFixture(t)
+
+	var count int
+	if err := f.pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM todos`).Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("unscoped query sees %d todos, want 0", count)
+	}
+}
+
+func TestRLSBlocksCrossUserWrites(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+
+	err := withUserScope(ctx, f.pool, f.bob.ID, func(tx pgx.Tx) error {
+		update, err := tx.Exec(ctx, `UPDATE todos SET title = 'pwned' WHERE id = $1`, f.todo.ID)
+		if err != nil {
+			return err
+		}
+		del, err := tx.Exec(ctx, `DELETE FROM todos WHERE id = $1`, f.todo.ID)
+		if err != nil {
+			return err
+		}
+		if update.RowsAffected() != 0 || del.RowsAffected() != 0 {
+			t.Errorf("bob changed alice's todo: updated %d, deleted %d", update.RowsAffected(), del.RowsAffected())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scoped writes: %v", err)
+	}
+
+	got, err := NewTodoRepository(f.pool, f.systemPool).GetByID(f.aliceCtx, f.todo.ID, f.alice.ID)
+	if err != nil {
+		t.Fatalf("alice lost her todo: %v", err)
+	}
+	if got.Title != f.todo.Title {
+		t.Errorf("title = %q, want %q", got.Title, f.todo.Title)
+	}
+}
+
+func TestRLSHoldsEvenWithForeignWorkspaceInContext(t *testing.T) {
+	f := newRLSFixture(t)
+
+	// Bob somehow ends up with alice's workspace as the active tenant
+	if _, err := NewTodoRepository(f.pool, f.systemPool).GetByID(f.aliceCtx, f.todo.ID, f.bob.ID); err == nil {
+		t.Error("bob read alice's todo through the repository")
+	}
+}
+
+func TestRLSHidesCommentsOfInvisibleTodos(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+
+	comment := &models.Comment{TodoID: f.todo.ID, UserID: f.alice.ID, Body: "only for me"}
+	if err := NewCommentRepository(f.pool).Create(ctx, comment); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+
+	var count int
+	withUserScope(ctx, f.pool, f.bob.ID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, `SELECT COUNT(*) FROM todo_comments`).Scan(&count)
+	})
+	if count != 0 {
+		t.Errorf("bob sees %d comments, want 0", count)
+	}
+}
+
+func TestRLSFollowsAcceptedShares(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+
+	_, err := f.admin.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'member');
+	`, f.todo.WorkspaceID, f.bob.ID)
+	if err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+	_, err = f.admin.Exec(ctx, `
+		INSERT INTO shares (todo_id, user_id, role, status, invited_by) VALUES ($1, $2, 'viewer', 'accepted', $3)
+	`, f.todo.ID, f.bob.ID, f.alice.ID)
+	if err != nil {
+		t.Fatalf("share: %v", err)
+	}
+
+	err = withUserScope(ctx, f.pool, f.bob.ID, func(tx pgx.Tx) error {
+		var title string
+		return tx.QueryRow(ctx, `SELECT title FROM todos WHERE id = $1`, f.todo.ID).Scan(&title)
+	})
+	if err != nil {
+		t.Errorf("bob cannot read a todo shared with him: %v", err)
+	}
+}
+
+func TestRLSCannotBeSwitchedOffBySetting(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+
+	// What the policies used to trust: any statement could set it
+	var count int
+	err := withUserScope(ctx, f.pool, f.bob.ID, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT set_config('app.bypass_rls', 'on', true)"); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx, `SELECT COUNT(*) FROM todos`).Scan(&count)
+	})
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("bob sees %d todos after setting app.bypass_rls, want 0", count)
+	}
+
+	err = withSystemScope(ctx, f.systemPool, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, `SELECT COUNT(*) FROM todos`).Scan(&count)
+	})
+	if err != nil {
+		t.Fatalf("system count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("the system scope sees %d todos, want 1", count)
+	}
+}
+
+func TestRLSHidesRowsOfLaterTables(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+
+	// Creating the project writes its workflow states in alice's scope
+	project := &models.Project{UserID: f.alice.ID, Name: "Plans"}
+	if err := NewProjectRepository(f.pool).Create(f.aliceCtx, project); err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	blocker := &models.Todo{UserID: f.alice.ID, Title: "First step", ProjectID: &project.ID}
+	if err := NewTodoRepository(f.pool, f.systemPool).Create(f.aliceCtx, blocker); err != nil {
+		t.Fatalf("create todo: %v", err)
+	}
+
+	_, err := f.admin.Exec(ctx, `
+		INSERT INTO saved_views (workspace_id, user_id, name) VALUES ($1, $2, 'Mine');
+		INSERT INTO undo_tokens (token_hash, user_id, workspace_id, action, todos, expires_at)
+			VALUES ('hash', $2, $1, 'delete', '[]', NOW() + INTERVAL '1 minute');
+		INSERT INTO notifications (user_id, type, todo_id, actor_id, message)
+			VALUES ($2, 'todo_assigned', $3, $2, 'secret');
+		INSERT INTO todo_state_transitions (todo_id, to_state, user_id) VALUES ($4, 'Doing', $2);
+		INSERT INTO todo_dependencies (todo_id, blocker_id, created_by) VALUES ($3, $4, $2);
+		INSERT INTO todo_tombstones (todo_id, workspace_id) VALUES (-1, $1);
+		INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint, expires_at)
+			VALUES ($2, 'key', repeat('0', 64), NOW() + INTERVAL '1 day');
+	`, f.todo.WorkspaceID, f.alice.ID, f.todo.ID, blocker.ID)
+	if err != nil {
+		t.Fatalf("insert rows: %v", err)
+	}
+
+	tables := []string{
+		"saved_views", "undo_tokens", "notifications", "workflow_states",
+		"todo_state_transitions", "todo_dependencies", "todo_tombstones", "idempotency_keys",
+	}
+	for _, table := range tables {
+		t.Run(table, func(t *testing.T) {
+			var bobCount, aliceCount int
+			withUserScope(ctx, f.pool, f.bob.ID, func(tx pgx.Tx) error {
+				return tx.QueryRow(ctx, `SELECT COUNT(*) FROM `+table).Scan(&bobCount)
+			})
+			withUserScope(ctx, f.pool, f.alice.ID, func(tx pgx.Tx) error {
+				return tx.QueryRow(ctx, `SELECT COUNT(*) FROM `+table).Scan(&aliceCount)
+			})
+			if bobCount != 0 {
+				t.Errorf("bob sees %d rows, want 0", bobCount)
+			}
+			if aliceCount == 0 {
+				t.Error("alice sees no rows")
+			}
+		})
+	}
+}
+
+func TestRLSLetsActorsNotifyOtherUsers(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+	notifications := NewNotificationRepository(f.pool)
+
+	sent := &models.Notification{UserID: f.alice.ID, Type: models.NotificationTodoAssigned, ActorID: &f.bob.ID, Message: "for alice"}
+	if err := notifications.Create(ctx, sent); err != nil {
+		t.Fatalf("bob notifies alice: %v", err)
+	}
+
+	// Nobody can write a notification in someone else's name
+	forged := &models.Notification{UserID: f.bob.ID, Type: models.NotificationTodoAssigned, ActorID: &f.alice.ID, Message: "from alice"}
+	err := withUserScope(ctx, f.pool, f.bob.ID, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO notifications (user_id, type, actor_id, message) VALUES ($1, $2, $3, $4)
+		`, forged.UserID, forged.Type, forged.ActorID, forged.Message)
+		return err
+	})
+	if err == nil {
+		t.Error("bob wrote a notification as alice")
+	}
+
+	got, total, err := notifications.GetByUserID(ctx, f.alice.ID, 1, 10, false)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 1 || len(got) != 1 || got[0].ID != sent.ID {
+		t.Errorf("alice got %d of %d notifications, want the one bob sent", len(got), total)
+	}
+}
+
+func TestRLSDependencyCycleThroughInvisibleTodo(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+	todos := NewTodoRepository(f.pool, f.systemPool)
+
+	_, err := f.admin.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, 'member');
+	`, f.todo.WorkspaceID, f.bob.ID)
+	if err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+	bobCtx := tenant.WithWorkspace(ctx, tenant.Workspace{ID: f.todo.WorkspaceID, Role: models.WorkspaceRoleMember})
+
+	blocker := &models.Todo{UserID: f.alice.ID, Title: "B"}
+	if err := todos.Create(f.aliceCtx, blocker); err != nil {
+		t.Fatalf("create B: %v", err)
+	}
+	private := &models.Todo{UserID: f.bob.ID, Title: "X"}
+	if err := todos.Create(bobCtx, private); err != nil {
+		t.Fatalf("create X: %v", err)
+	}
+
+	// B is blocked by X, which is blocked by A; alice can't see X
+	_, err = f.admin.Exec(ctx, `
+		INSERT INTO todo_dependencies (todo_id, blocker_id) VALUES ($1, $2), ($2, $3)
+	`, blocker.ID, private.ID, f.todo.ID)
+	if err != nil {
+		t.Fatalf("insert dependencies: %v", err)
+	}
+
+	err = todos.AddDependency(f.aliceCtx, f.todo.ID, blocker.ID, f.alice.ID)
+	if !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("A blocked by B: got err %v, want %v", err, ErrDependencyCycle)
+	}
+}
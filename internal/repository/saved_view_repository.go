@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+var ErrDuplicateViewName = errors.New("a saved view with this name already exists")
+
+type SavedViewRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSavedViewRepository(db *pgxpool.Pool) *SavedViewRepository {
+	return &SavedViewRepository{db: db}
+}
+
+// Views belong to one user in one workspace, so every query filters on both.
+const savedViewColumns = `id, workspace_id, user_id, name, filter, sort, created_at, updated_at`
+
+func scanSavedView(row pgx.Row, view *models.SavedView) error {
+	return row.Scan(
+		&view.ID,
+		&view.WorkspaceID,
+		&view.UserID,
+		&view.Name,
+		&view.Filter,
+		&view.Sort,
+		&view.CreatedAt,
+		&view.UpdatedAt,
+	)
+}
+
+func (r *SavedViewRepository) Create(ctx context.Context, view *models.SavedView) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO saved_views (workspace_id, user_id, name, filter, sort)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	view.WorkspaceID = workspaceID
+	err = withUserScope(ctx, r.db, view.UserID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, workspaceID, view.UserID, view.Name, view.Filter, view.Sort).
+			Scan(&view.ID, &view.CreatedAt, &view.UpdatedAt)
+	})
+	if isUniqueViolation(err) {
+		return ErrDuplicateViewName
+	}
+	return err
+}
+
+func (r *SavedViewRepository) GetByID(ctx context.Context, id, userID int) (*models.SavedView, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + savedViewColumns + ` FROM saved_views WHERE id = $1 AND user_id = $2 AND workspace_id = $3`
+
+	view := &models.SavedView{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return scanSavedView(tx.QueryRow(ctx, query, id, userID, workspaceID), view)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("saved view not found")
+		}
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (r *SavedViewRepository) GetByUserID(ctx context.Context, userID int) ([]models.SavedView, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + savedViewColumns + `
+		FROM saved_views
+		WHERE user_id = $1 AND workspace_id = $2
+		ORDER BY name ASC, id ASC
+	`
+
+	views := []models.SavedView{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, userID, workspaceID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var view models.SavedView
+			if err := scanSavedView(rows, &view); err != nil {
+				return err
+			}
+			views = append(views, view)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+func (r *SavedViewRepository) Update(ctx context.Context, view *models.SavedView) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE saved_views
+		SET name = $1, filter = $2, sort = $3
+		WHERE id = $4 AND user_id = $5 AND workspace_id = $6
+		RETURNING updated_at
+	`
+
+	err = withUserScope(ctx, r.db, view.UserID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, view.Name, view.Filter, view.Sort, view.ID, view.UserID, workspaceID).
+			Scan(&view.UpdatedAt)
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateViewName
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("saved view not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *SavedViewRepository) Delete(ctx context.Context, id, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM saved_views WHERE id = $1 AND user_id = $2 AND workspace_id = $3`
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, id, userID, workspaceID)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return errors.New("saved view not found")
+		}
+		return nil
+	})
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
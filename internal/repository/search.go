@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+)
+
+// headlineOptions configures ts_headline for search snippets.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// htmlEscapeSQL escapes the text expr evaluates to for HTML. Snippets are
+// built from the escaped text, so the only markup in them is the <mark> tags
+// ts_headline adds, never anything a todo's author stored.
+func htmlEscapeSQL(expr string) string {
+	return `replace(replace(replace(replace(replace(` + expr +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
+
+// prefixTSQuery turns free text into a to_tsquery expression that requires every
+// word and treats the last one as a prefix, so "buy mil" already finds "buy milk".
+// Anything that is not a letter or digit separates words, which keeps tsquery
+// operators in the input from being interpreted. An input without words yields "".
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+	return strings.Join(words, " & ")
+}
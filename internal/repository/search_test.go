@@ -0,0 +1,25 @@
+package repository
+
+import "testing"
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"milk", "milk:*"},
+		{"buy mil", "buy & mil:*"},
+		{"  call   mom ", "call & mom:*"},
+		{"fix: a|b & !c", "fix & a & b & c:*"},
+		{"o'reilly", "o & reilly:*"},
+		{"résumé 2024", "résumé & 2024:*"},
+		{"!!! ", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := prefixTSQuery(tt.in); got != tt.want {
+			t.Errorf("prefixTSQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
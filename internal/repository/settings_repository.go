@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+var ErrUnsupportedSearchLanguage = errors.New("unsupported search language")
+
+type SettingsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSettingsRepository(db *pgxpool.Pool) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+func (r *SettingsRepository) Get(ctx context.Context, userID int) (*models.UserSettings, error) {
+	query := `SELECT search_language::text, blocked_completion, auto_archive_days FROM users WHERE id = $1`
+
+	settings := &models.UserSettings{}
+	err := r.db.QueryRow(ctx, query, userID).Scan(&settings.SearchLanguage, &settings.BlockedCompletion, &settings.AutoArchiveDays)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// Update saves the settings. Changing the search language re-indexes every todo
+// the user owns, so old todos stay findable with the new language.
+func (r *SettingsRepository) Update(ctx context.Context, userID int, settings *models.UserSettings) error {
+	if settings.BlockedCompletion == "" {
+		settings.BlockedCompletion = models.BlockedCompletionWarn
+	}
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		var supported bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = $1)`, settings.SearchLanguage).
+			Scan(&supported)
+		if err != nil {
+			return err
+		}
+		if !supported {
+			return ErrUnsupportedSearchLanguage
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE users SET search_language = $2::regconfig, blocked_completion = $3, auto_archive_days = $4
+			WHERE id = $1
+		`, userID, settings.SearchLanguage, settings.BlockedCompletion, settings.AutoArchiveDays)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE todos SET search_language = $2::regconfig
+			WHERE user_id = $1 AND search_language <> $2::regconfig
+		`, userID, settings.SearchLanguage)
+		return err
+	})
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+type ShareLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewShareLinkRepository(db *pgxpool.Pool) *ShareLinkRepository {
+	return &ShareLinkRepository{db: db}
+}
+
+const shareLinkColumns = `
+	l.id, l.user_id,
+	CASE WHEN l.todo_id IS NOT NULL THEN 'todo' ELSE 'project' END,
+	COALESCE(l.todo_id, l.project_id),
+	l.token_hash, l.password_hash, l.expires_at, l.revoked_at, l.access_count, l.last_accessed_at, l.created_at
+`
+
+func scanShareLink(row pgx.Row, link *models.ShareLink) error {
+	err := row.Scan(
+		&link.ID,
+		&link.UserID,
+		&link.ResourceType,
+		&link.ResourceID,
+		&link.TokenHash,
+		&link.PasswordHash,
+		&link.ExpiresAt,
+		&link.RevokedAt,
+		&link.AccessCount,
+		&link.LastAccessedAt,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	link.HasPassword = link.PasswordHash != nil
+	return nil
+}
+
+func (r *ShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	var todoID, projectID *int
+	switch link.ResourceType {
+	case models.ShareTypeTodo:
+		todoID = &link.ResourceID
+	case models.ShareTypeProject:
+		projectID = &link.ResourceID
+	default:
+		return errors.New("invalid share type")
+	}
+
+	query := `
+		INSERT INTO share_links (token_hash, todo_id, project_id, user_id, password_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		link.TokenHash,
+		todoID,
+		projectID,
+		link.UserID,
+		link.PasswordHash,
+		link.ExpiresAt,
+	).Scan(&link.ID, &link.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	link.HasPassword = link.PasswordHash != nil
+	return nil
+}
+
+// GetActiveByTokenHash returns a link that is neither revoked nor expired.
+func (r *ShareLinkRepository) GetActiveByTokenHash(ctx context.Context, tokenHash string) (*models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + `
+		FROM share_links l
+		WHERE l.token_hash = $1
+		  AND l.revoked_at IS NULL
+		  AND (l.expires_at IS NULL OR l.expires_at > NOW())
+	`
+
+	link := &models.ShareLink{}
+	if err := scanShareLink(r.db.QueryRow(ctx, query, tokenHash), link); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("share link not found")
+		}
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// GetByUserID lists every link the user created, including revoked and expired ones,
+// so they can see how often each was opened.
+func (r *ShareLinkRepository) GetByUserID(ctx context.Context, userID int) ([]models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + `
+		FROM share_links l
+		WHERE l.user_id = $1
+		ORDER BY l.created_at DESC, l.id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		var link models.ShareLink
+		if err := scanShareLink(rows, &link); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+func (r *ShareLinkRepository) Revoke(ctx context.Context, id, userID int) error {
+	query := `
+		UPDATE share_links
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("share link not found")
+	}
+
+	return nil
+}
+
+func (r *ShareLinkRepository) RecordAccess(ctx context.Context, id int) error {
+	query := `
+		UPDATE share_links
+		SET access_count = access_count + 1, last_accessed_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+// GetView loads the sanitized content behind a link. The link's creator must still
+// have access to what they shared; losing it silently disables the link. The
+// queries run in the creator's row-level security scope, so the link can never
+// expose more than its creator could see.
+func (r *ShareLinkRepository) GetView(ctx context.Context, link *models.ShareLink) (*models.SharedView, error) {
+	view := &models.SharedView{ResourceType: link.ResourceType}
+
+	err := withUserScope(ctx, r.db, link.UserID, func(tx pgx.Tx) error {
+		if link.ResourceType == models.ShareTypeTodo {
+			query := `
+				SELECT t.title, t.description, t.completed, t.created_at, t.updated_at
+				FROM todos t
+				JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $2
+				WHERE t.id = $1 AND t.deleted_at IS NULL
+			`
+
+			todo := &models.PublicTodo{}
+			err := tx.QueryRow(ctx, query, link.ResourceID, link.UserID).
+				Scan(&todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+			if err != nil {
+				return err
+			}
+
+			view.Todo = todo
+			return nil
+		}
+
+		project := &models.PublicProject{Todos: []models.PublicTodo{}}
+		query := `
+			SELECT p.name
+			FROM projects p
+			JOIN project_access a ON a.project_id = p.id AND a.user_id = $2
+			WHERE p.id = $1
+		`
+		if err := tx.QueryRow(ctx, query, link.ResourceID, link.UserID).Scan(&project.Name); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT title, description, completed, created_at, updated_at
+			FROM todos
+			WHERE project_id = $1 AND deleted_at IS NULL AND archived_at IS NULL
+			ORDER BY created_at ASC, id ASC
+		`, link.ResourceID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var todo models.PublicTodo
+			if err := rows.Scan(&todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+				return err
+			}
+			project.Todos = append(project.Todos, todo)
+		}
+
+		view.Project = project
+		return rows.Err()
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("shared " + link.ResourceType + " not found")
+		}
+		return nil, err
+	}
+
+	return view, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+type ShareRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewShareRepository(db *pgxpool.Pool) *ShareRepository {
+	return &ShareRepository{db: db}
+}
+
+const shareColumns = `
+	s.id,
+	CASE WHEN s.todo_id IS NOT NULL THEN 'todo' ELSE 'project' END,
+	COALESCE(s.todo_id, s.project_id),
+	s.user_id, u.name, u.email, s.role, s.status, s.invited_by, s.created_at, s.responded_at
+`
+
+func scanShare(row pgx.Row, share *models.Share) error {
+	return row.Scan(
+		&share.ID,
+		&share.ResourceType,
+		&share.ResourceID,
+		&share.UserID,
+		&share.Name,
+		&share.Email,
+		&share.Role,
+		&share.Status,
+		&share.InvitedBy,
+		&share.CreatedAt,
+		&share.RespondedAt,
+	)
+}
+
+func (r *ShareRepository) queryShares(ctx context.Context, where string, args ...any) ([]models.Share, error) {
+	query := `SELECT ` + shareColumns + `
+		FROM shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE ` + where + `
+		ORDER BY s.created_at ASC, s.id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.Share{}
+	for rows.Next() {
+		var share models.Share
+		if err := scanShare(rows, &share); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// Invite creates a pending invitation, or updates the role of an existing one.
+// Re-inviting someone who declined puts the invitation back to pending, while
+// changing the role of an accepted share keeps it accepted.
+func (r *ShareRepository) Invite(ctx context.Context, share *models.Share) error {
+	var todoID, projectID *int
+	conflictTarget := "(todo_id, user_id)"
+	switch share.ResourceType {
+	case models.ShareTypeTodo:
+		todoID = &share.ResourceID
+	case models.ShareTypeProject:
+		projectID = &share.ResourceID
+		conflictTarget = "(project_id, user_id)"
+	default:
+		return errors.New("invalid share type")
+	}
+
+	query := `
+		INSERT INTO shares AS s (todo_id, project_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ` + conflictTarget + ` DO UPDATE SET
+			role = EXCLUDED.role,
+			invited_by = EXCLUDED.invited_by,
+			status = CASE WHEN s.status = 'accepted' THEN 'accepted' ELSE 'pending' END,
+			responded_at = CASE WHEN s.status = 'accepted' THEN s.responded_at ELSE NULL END
+		RETURNING id, status, created_at, responded_at
+	`
+
+	return r.db.QueryRow(ctx, query, todoID, projectID, share.UserID, share.Role, share.InvitedBy).
+		Scan(&share.ID, &share.Status, &share.CreatedAt, &share.RespondedAt)
+}
+
+func (r *ShareRepository) GetByID(ctx context.Context, id int) (*models.Share, error) {
+	query := `SELECT ` + shareColumns + `
+		FROM shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.id = $1
+	`
+
+	share := &models.Share{}
+	if err := scanShare(r.db.QueryRow(ctx, query, id), share); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("share not found")
+		}
+		return nil, err
+	}
+
+	return share, nil
+}
+
+// ListByResource returns every share (in any state) on a todo or project.
+func (r *ShareRepository) ListByResource(ctx context.Context, resourceType string, resourceID int) ([]models.Share, error) {
+	switch resourceType {
+	case models.ShareTypeTodo:
+		return r.queryShares(ctx, "s.todo_id = $1", resourceID)
+	case models.ShareTypeProject:
+		return r.queryShares(ctx, "s.project_id = $1", resourceID)
+	}
+	return nil, errors.New("invalid share type")
+}
+
+// ListPendingForUser returns the invitations waiting for the user's answer.
+func (r *ShareRepository) ListPendingForUser(ctx context.Context, userID int) ([]models.Share, error) {
+	return r.queryShares(ctx, "s.user_id = $1 AND s.status = $2", userID, models.ShareStatusPending)
+}
+
+// Respond accepts or declines a pending invitation addressed to userID.
+func (r *ShareRepository) Respond(ctx context.Context, id, userID int, accept bool) (*models.Share, error) {
+	status := models.ShareStatusDeclined
+	if accept {
+		status = models.ShareStatusAccepted
+	}
+
+	query := `
+		UPDATE shares
+		SET status = $1, responded_at = $2
+		WHERE id = $3 AND user_id = $4 AND status = 'pending'
+	`
+
+	result, err := r.db.Exec(ctx, query, status, time.Now(), id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected() == 0 {
+		return nil, errors.New("invitation not found")
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *ShareRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM shares WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("share not found")
+	}
+
+	return nil
+}
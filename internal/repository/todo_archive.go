@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+// SetArchived archives or unarchives the todo if userID is at least an editor of it.
+func (r *TodoRepository) SetArchived(ctx context.Context, todo *models.Todo, archived bool, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE todos t
+		SET archived_at = CASE WHEN $1 THEN COALESCE(t.archived_at, NOW()) END
+		WHERE t.id = $2 AND t.workspace_id = $5 AND t.deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $3 AND a.role_rank >= $4
+		)
+		RETURNING archived_at, updated_at
+	`
+
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, archived, todo.ID, userID, models.RoleRank(models.RoleEditor), workspaceID).
+			Scan(&todo.ArchivedAt, &todo.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("todo not found or unauthorized")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ArchiveCompleted archives the user's own completed todos last changed before
+// the cutoff and reports how many it archived.
+func (r *TodoRepository) ArchiveCompleted(ctx context.Context, userID int, before time.Time) (int, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE todos t
+		SET archived_at = NOW()
+		WHERE t.workspace_id = $3 AND t.completed AND t.updated_at < $4
+		  AND t.archived_at IS NULL AND t.deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $1 AND a.role_rank >= $2
+		)
+	`
+
+	var archived int
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, userID, models.RoleRank(models.RoleOwner), workspaceID, before)
+		archived = int(result.RowsAffected())
+		return err
+	})
+	return archived, err
+}
+
+// AutoArchive applies every user's automatic archiving rule (see
+// UserSettings.AutoArchiveDays) to the todos they own, and reports how many
+// todos it archived. It runs as a background job.
+func (r *TodoRepository) AutoArchive(ctx context.Context) (int, error) {
+	query := `
+		UPDATE todos t
+		SET archived_at = NOW()
+		FROM users u
+		WHERE u.id = t.user_id AND u.auto_archive_days IS NOT NULL
+		  AND t.completed AND t.updated_at < NOW() - make_interval(days => u.auto_archive_days)
+		  AND t.archived_at IS NULL AND t.deleted_at IS NULL
+	`
+
+	var archived int
+	err := withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query)
+		archived = int(result.RowsAffected())
+		return err
+	})
+	return archived, err
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+// ErrBulkAborted means an atomic bulk change was rolled back because one of
+// its writes failed.
+var ErrBulkAborted = errors.New("bulk change rolled back")
+
+// GetByIDs returns the todos among ids that userID has some role on, keyed by
+// ID, with that role, in one query. IDs the user can't see are left out.
+func (r *TodoRepository) GetByIDs(ctx context.Context, ids []int, userID int) (map[int]*models.Todo, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + todoColumns + `
+		FROM todos t
+		JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $2
+		WHERE t.id = ANY($1) AND t.workspace_id = $3 AND t.deleted_at IS NULL
+	`
+
+	todos := make(map[int]*models.Todo, len(ids))
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, ids, userID, workspaceID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			todo := &models.Todo{}
+			if err := scanTodo(rows, todo); err != nil {
+				return err
+			}
+			todos[todo.ID] = todo
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// ApplyBulk makes the writes in order in one transaction, with the same access
+// and version checks as Create, Update and Delete. It returns the error of each write that
+// failed, by index.
+//
+// If atomic, the first failing write rolls everything back and ApplyBulk
+// returns ErrBulkAborted. Otherwise each write runs in its own savepoint, so a
+// failed one is undone on its own and the others are still committed.
+func (r *TodoRepository) ApplyBulk(ctx context.Context, userID int, writes []models.BulkWrite, atomic bool) ([]error, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// A todo written more than once is at the version its last write left it
+	versions := map[int]int{}
+	apply := func(tx pgx.Tx, write models.BulkWrite) error {
+		if version, ok := versions[write.Todo.ID]; ok && write.Op != models.BulkWriteInsert {
+			write.Todo.Version = version
+		}
+		switch write.Op {
+		case models.BulkWriteInsert:
+			return insertTodo(ctx, tx, workspaceID, write.Todo)
+		case models.BulkWriteUpdate:
+			if err := updateTodo(ctx, tx, workspaceID, write.Todo, userID); err != nil {
+				return err
+			}
+			versions[write.Todo.ID] = write.Todo.Version
+			return nil
+		case models.BulkWriteTrash:
+			version, err := trashTodo(ctx, tx, workspaceID, write.Todo.ID, write.Todo.Version, userID)
+			if err != nil {
+				return err
+			}
+			write.Todo.Version = version
+			versions[write.Todo.ID] = version
+			return nil
+		}
+		return fmt.Errorf("unknown bulk write %q", write.Op)
+	}
+
+	errs := make([]error, len(writes))
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		for i, write := range writes {
+			if atomic {
+				if err := apply(tx, write); err != nil {
+					errs[i] = err
+					return ErrBulkAborted
+				}
+				continue
+			}
+
+			savepoint, err := tx.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			if err := apply(savepoint, write); err != nil {
+				errs[i] = err
+				if err := savepoint.Rollback(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := savepoint.Commit(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return errs, err
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+var (
+	ErrBlockerNotFound     = errors.New("blocking todo not found")
+	ErrDuplicateDependency = errors.New("the todo already depends on this one")
+	// ErrDependencyCycle refuses a dependency that would make a todo (indirectly) block itself
+	ErrDependencyCycle = errors.New("the dependency would create a cycle")
+)
+
+// AddDependency records that todoID is blocked by blockerID. userID must be an
+// editor of todoID and able to see blockerID. Dependencies in a workspace are
+// added one at a time under an advisory lock, so two concurrent requests can't
+// each close half of a cycle. The cycle check follows every dependency,
+// including ones between todos the user can't see, so it runs in the system
+// scope while the user's transaction holds the lock.
+func (r *TodoRepository) AddDependency(ctx context.Context, todoID, blockerID, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	if todoID == blockerID {
+		return ErrDependencyCycle
+	}
+
+	accessQuery := `
+		SELECT
+			EXISTS (
+				SELECT 1 FROM todos t
+				JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $3 AND a.role_rank >= $4
+				WHERE t.id = $1 AND t.workspace_id = $5 AND t.deleted_at IS NULL
+			),
+			EXISTS (
+				SELECT 1 FROM todos t
+				JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $3
+				WHERE t.id = $2 AND t.workspace_id = $5 AND t.deleted_at IS NULL
+			)
+	`
+
+	// Does the blocker already (transitively) wait for the todo?
+	cycleQuery := `
+		WITH RECURSIVE upstream(id) AS (
+			SELECT blocker_id FROM todo_dependencies WHERE todo_id = $1
+			UNION
+			SELECT d.blocker_id FROM todo_dependencies d JOIN upstream u ON d.todo_id = u.id
+		)
+		SELECT EXISTS (SELECT 1 FROM upstream WHERE id = $2)
+	`
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('todo_dependencies'), $1)`, workspaceID); err != nil {
+			return err
+		}
+
+		var canEdit, canSeeBlocker bool
+		err := tx.QueryRow(ctx, accessQuery, todoID, blockerID, userID, models.RoleRank(models.RoleEditor), workspaceID).
+			Scan(&canEdit, &canSeeBlocker)
+		if err != nil {
+			return err
+		}
+		if !canEdit {
+			return errors.New("todo not found or unauthorized")
+		}
+		if !canSeeBlocker {
+			return ErrBlockerNotFound
+		}
+
+		var cycle bool
+		err = withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+			return tx.QueryRow(ctx, cycleQuery, blockerID, todoID).Scan(&cycle)
+		})
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO todo_dependencies (todo_id, blocker_id, created_by)
+			VALUES ($1, $2, $3)
+		`, todoID, blockerID, userID)
+		if isUniqueViolation(err) {
+			return ErrDuplicateDependency
+		}
+		return err
+	})
+}
+
+// RemoveDependency drops the dependency of todoID on blockerID if userID is at
+// least an editor of todoID.
+func (r *TodoRepository) RemoveDependency(ctx context.Context, todoID, blockerID, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM todo_dependencies d
+		USING todos t
+		WHERE d.todo_id = $1 AND d.blocker_id = $2
+		  AND t.id = d.todo_id AND t.workspace_id = $5 AND t.deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $3 AND a.role_rank >= $4
+		)
+	`
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, todoID, blockerID, userID, models.RoleRank(models.RoleEditor), workspaceID)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return errors.New("dependency not found or unauthorized")
+		}
+
+		return nil
+	})
+}
+
+// GetDependencies returns the todos todoID is blocked by and the ones it
+// blocks, completed or not, as far as userID can see them.
+func (r *TodoRepository) GetDependencies(ctx context.Context, todoID, userID int) (*models.TodoDependencies, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	blockedByQuery := `
+		SELECT ` + todoColumns + `
+		FROM todo_dependencies dep
+		JOIN todos t ON t.id = dep.blocker_id
+		JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $2
+		WHERE dep.todo_id = $1 AND t.workspace_id = $3 AND t.deleted_at IS NULL
+		ORDER BY t.id ASC
+	`
+	blockingQuery := `
+		SELECT ` + todoColumns + `
+		FROM todo_dependencies dep
+		JOIN todos t ON t.id = dep.todo_id
+		JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $2
+		WHERE dep.blocker_id = $1 AND t.workspace_id = $3 AND t.deleted_at IS NULL
+		ORDER BY t.id ASC
+	`
+
+	deps := &models.TodoDependencies{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		for _, list := range []struct {
+			query string
+			todos *[]models.Todo
+		}{
+			{blockedByQuery, &deps.BlockedBy},
+			{blockingQuery, &deps.Blocking},
+		} {
+			rows, err := tx.Query(ctx, list.query, todoID, userID, workspaceID)
+			if err != nil {
+				return err
+			}
+			*list.todos, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Todo, error) {
+				var todo models.Todo
+				err := scanTodo(row, &todo)
+				return todo, err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deps, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pigeio/todo-api/internal/filter"
+)
+
+// todoFilterColumns maps the fields of models.TodoFilterFields to columns.
+// A field missing here is refused even if the parser accepted it.
+var todoFilterColumns = map[string]string{
+	"completed":   "t.completed",
+	"title":       "t.title",
+	"description": "t.description",
+	"project":     "t.project_id",
+	"assignee":    "t.assignee_id",
+	"state":       "(SELECT ws.name FROM workflow_states ws WHERE ws.id = t.state_id)",
+	"blocked":     todoBlockedSQL,
+	"owner":       "t.user_id",
+	"created_at":  "t.created_at",
+	"updated_at":  "t.updated_at",
+}
+
+// compileTodoFilter turns a parsed filter into a SQL condition. Values never
+// become part of the SQL text: each is appended to args and referenced by its
+// placeholder. Relative times are resolved against now; `me` is userID.
+func compileTodoFilter(expr filter.Expr, userID int, now time.Time, args []any) (string, []any, error) {
+	c := &filterCompiler{userID: userID, now: now, args: args}
+	sql, err := c.compile(expr)
+	return sql, c.args, err
+}
+
+type filterCompiler struct {
+	userID int
+	now    time.Time
+	args   []any
+}
+
+func (c *filterCompiler) bind(value any) string {
+	c.args = append(c.args, value)
+	return fmt.Sprintf("$%d", len(c.args))
+}
+
+func (c *filterCompiler) compile(expr filter.Expr) (string, error) {
+	switch e := expr.(type) {
+	case filter.And:
+		return c.compileBinary(e.Left, e.Right, "AND")
+	case filter.Or:
+		return c.compileBinary(e.Left, e.Right, "OR")
+	case filter.Not:
+		inner, err := c.compile(e.Expr)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + inner + ")", nil
+	case filter.Comparison:
+		return c.compileComparison(e)
+	}
+	return "", fmt.Errorf("unsupported filter node %T", expr)
+}
+
+func (c *filterCompiler) compileBinary(left, right filter.Expr, op string) (string, error) {
+	l, err := c.compile(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := c.compile(right)
+	if err != nil {
+		return "", err
+	}
+	return "(" + l + " " + op + " " + r + ")", nil
+}
+
+// Comparisons are written to never yield NULL, so NOT behaves as users expect
+// (NOT project:1 includes todos without a project).
+func (c *filterCompiler) compileComparison(cmp filter.Comparison) (string, error) {
+	column, ok := todoFilterColumns[cmp.Field]
+	if !ok {
+		return "", fmt.Errorf("filter field %q has no column", cmp.Field)
+	}
+
+	var value any
+	switch cmp.Value.Kind {
+	case filter.KindNull:
+		if cmp.Op == filter.OpNotEqual {
+			return column + " IS NOT NULL", nil
+		}
+		return column + " IS NULL", nil
+	case filter.KindBool:
+		value = cmp.Value.Bool
+	case filter.KindNumber:
+		value = cmp.Value.Number
+	case filter.KindMe:
+		value = c.userID
+	case filter.KindText:
+		if cmp.Op == filter.OpMatch {
+			return "COALESCE(" + column + ", '') ILIKE " + c.bind("%"+escapeLike(cmp.Value.Text)+"%"), nil
+		}
+		value = cmp.Value.Text
+	case filter.KindTime:
+		at := cmp.Value.Time.Resolve(c.now)
+		if cmp.Op == filter.OpMatch && cmp.Value.Time.DateOnly {
+			return fmt.Sprintf("(%[1]s >= %[2]s AND %[1]s < %[3]s)", column, c.bind(at), c.bind(at.AddDate(0, 0, 1))), nil
+		}
+		value = at
+	default:
+		return "", fmt.Errorf("unsupported filter value kind %d", cmp.Value.Kind)
+	}
+
+	switch cmp.Op {
+	case filter.OpMatch, filter.OpEqual:
+		return column + " IS NOT DISTINCT FROM " + c.bind(value), nil
+	case filter.OpNotEqual:
+		return column + " IS DISTINCT FROM " + c.bind(value), nil
+	case filter.OpLess, filter.OpLessEqual, filter.OpGreater, filter.OpGreaterEqual:
+		return column + " " + string(cmp.Op) + " " + c.bind(value), nil
+	}
+	return "", fmt.Errorf("unsupported filter operator %q", cmp.Op)
+}
+
+// escapeLike makes s match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pigeio/todo-api/internal/filter"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+func TestCompileTodoFilter(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+	today := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in       string
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			"completed:false AND (title:50%_off OR assignee:me)",
+			"(t.completed IS NOT DISTINCT FROM $3 AND (COALESCE(t.title, '') ILIKE $4 OR t.assignee_id IS NOT DISTINCT FROM $5))",
+			[]any{false, `%50\%\_off%`, 7},
+		},
+		{
+			"NOT project:none",
+			"NOT (t.project_id IS NULL)",
+			nil,
+		},
+		{
+			"project!=3 OR created_at:today",
+			"(t.project_id IS DISTINCT FROM $3 OR (t.created_at >= $4 AND t.created_at < $5))",
+			[]any{3, today, today.AddDate(0, 0, 1)},
+		},
+		{
+			`updated_at>=-12h AND description="it's; DROP TABLE todos"`,
+			"(t.updated_at >= $3 AND t.description IS NOT DISTINCT FROM $4)",
+			[]any{now.Add(-12 * time.Hour), "it's; DROP TABLE todos"},
+		},
+	}
+
+	for _, tt := range tests {
+		expr, err := filter.Parse(tt.in, models.TodoFilterFields)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.in, err)
+		}
+
+		sql, args, err := compileTodoFilter(expr, 7, now, []any{1, 2})
+		if err != nil {
+			t.Fatalf("compileTodoFilter(%q): %v", tt.in, err)
+		}
+		if sql != tt.wantSQL {
+			t.Errorf("compileTodoFilter(%q) SQL =\n%s\nwant\n%s", tt.in, sql, tt.wantSQL)
+		}
+		if !reflect.DeepEqual(args[2:], append([]any{}, tt.wantArgs...)) {
+			t.Errorf("compileTodoFilter(%q) args = %v, want %v", tt.in, args[2:], tt.wantArgs)
+		}
+	}
+}
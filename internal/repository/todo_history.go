@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+// Revisions are written by a trigger on todos (see migrations/000017), so
+// every change is recorded whichever code path makes it.
+
+const revisionColumns = `r.id, r.todo_id, r.revision, r.action, r.user_id, r.changes, r.snapshot, r.created_at`
+
+func scanRevision(row pgx.Row, rev *models.TodoRevision, extra ...any) error {
+	dest := []any{
+		&rev.ID,
+		&rev.TodoID,
+		&rev.Revision,
+		&rev.Action,
+		&rev.UserID,
+		&rev.Changes,
+		&rev.Snapshot,
+		&rev.CreatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// ListRevisions returns a page of the todo's history, newest first, if userID can see the todo.
+func (r *TodoRepository) ListRevisions(ctx context.Context, todoID, userID, page, limit int) ([]models.TodoRevision, int, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where := `
+		FROM todo_revisions r
+		JOIN todo_access a ON a.todo_id = r.todo_id AND a.user_id = $2
+		WHERE r.todo_id = $1 AND r.workspace_id = $3
+	`
+	query := `
+		SELECT ` + revisionColumns + `
+		` + where + `
+		ORDER BY r.revision DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	revisions := []models.TodoRevision{}
+	var total int
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) "+where, todoID, userID, workspaceID).Scan(&total); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, todoID, userID, workspaceID, limit, (page-1)*limit)
+		if err != nil {
+			return err
+		}
+		revisions, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.TodoRevision, error) {
+			var rev models.TodoRevision
+			err := scanRevision(row, &rev)
+			return rev, err
+		})
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, total, nil
+}
+
+// GetRevision returns one revision of the todo if userID can see the todo.
+func (r *TodoRepository) GetRevision(ctx context.Context, todoID, revision, userID int) (*models.TodoRevision, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + revisionColumns + `
+		FROM todo_revisions r
+		JOIN todo_access a ON a.todo_id = r.todo_id AND a.user_id = $3
+		WHERE r.todo_id = $1 AND r.revision = $2 AND r.workspace_id = $4
+	`
+
+	rev := &models.TodoRevision{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return scanRevision(tx.QueryRow(ctx, query, todoID, revision, userID, workspaceID), rev)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+// ListActivity returns a page of the changes to every todo userID can see in
+// the workspace, newest first, including todos in the trash.
+func (r *TodoRepository) ListActivity(ctx context.Context, userID, page, limit int) ([]models.TodoRevision, int, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where := `
+		FROM todo_revisions r
+		JOIN todo_access a ON a.todo_id = r.todo_id AND a.user_id = $1
+		JOIN todos t ON t.id = r.todo_id
+		WHERE r.workspace_id = $2 AND r.action <> '` + models.RevisionSnapshot + `'
+	`
+	query := `
+		SELECT ` + revisionColumns + `, t.title
+		` + where + `
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	activity := []models.TodoRevision{}
+	var total int
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) "+where, userID, workspaceID).Scan(&total); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, userID, workspaceID, limit, (page-1)*limit)
+		if err != nil {
+			return err
+		}
+		activity, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.TodoRevision, error) {
+			var rev models.TodoRevision
+			err := scanRevision(row, &rev, &rev.TodoTitle)
+			rev.Snapshot = nil
+			return rev, err
+		})
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return activity, total, nil
+}
@@ -5,51 +5,139 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
 )
 
 type TodoRepository struct {
 	db *pgxpool.Pool
+	// systemDB may bypass row-level security; only withSystemScope uses it
+	systemDB *pgxpool.Pool
 }
 
-func NewTodoRepository(db *pgxpool.Pool) *TodoRepository {
-	return &TodoRepository{db: db}
+func NewTodoRepository(db, systemDB *pgxpool.Pool) *TodoRepository {
+	return &TodoRepository{db: db, systemDB: systemDB}
+}
+
+// Every read goes through the todo_access view, which resolves the caller's
+// effective role from ownership, direct shares and project shares.
+//
+// Every query is also pinned to the active workspace taken from the context
+// (see package tenant); without one, the repository refuses to run. On top of
+// that, each one runs through withUserScope so row-level security backs up
+// the hand-written predicates.
+const todoColumns = `t.id, t.workspace_id, t.user_id, t.project_id, t.assignee_id, t.title, t.description, t.completed, ` +
+	`t.state_id, (SELECT ws.name FROM workflow_states ws WHERE ws.id = t.state_id) AS state, ` +
+	todoBlockersSQL + ` AS blocked_by, t.position, t.archived_at, t.created_at, t.updated_at, t.version, a.role_rank`
+
+// todoBlockersSQL lists the open todos t is blocked by, and todoBlockedSQL
+// tells whether there are any. Completed blockers no longer block. Under
+// row-level security only blockers the user can see count.
+const (
+	todoBlockersSQL = `ARRAY(SELECT d.blocker_id FROM todo_dependencies d JOIN todos b ON b.id = d.blocker_id ` +
+		`WHERE d.todo_id = t.id AND NOT b.completed AND b.deleted_at IS NULL ORDER BY d.blocker_id)`
+	todoBlockedSQL = `EXISTS (SELECT 1 FROM todo_dependencies d JOIN todos b ON b.id = d.blocker_id ` +
+		`WHERE d.todo_id = t.id AND NOT b.completed AND b.deleted_at IS NULL)`
+)
+
+func scanTodo(row pgx.Row, todo *models.Todo, extra ...any) error {
+	var roleRank int
+	var position *string
+	dest := []any{
+		&todo.ID,
+		&todo.WorkspaceID,
+		&todo.UserID,
+		&todo.ProjectID,
+		&todo.AssigneeID,
+		&todo.Title,
+		&todo.Description,
+		&todo.Completed,
+		&todo.StateID,
+		&todo.State,
+		&todo.BlockedBy,
+		&position,
+		&todo.ArchivedAt,
+		&todo.CreatedAt,
+		&todo.UpdatedAt,
+		&todo.Version,
+		&roleRank,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if position != nil {
+		todo.Position = *position
+	}
+	todo.Blocked = len(todo.BlockedBy) > 0
+	todo.Role = models.RoleFromRank(roleRank)
+	return nil
 }
 
 func (r *TodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return withUserScope(ctx, r.db, todo.UserID, func(tx pgx.Tx) error {
+		return insertTodo(ctx, tx, workspaceID, todo)
+	})
+}
+
+// insertTodo saves a new todo at the end of the manual order.
+func insertTodo(ctx context.Context, tx pgx.Tx, workspaceID int, todo *models.Todo) error {
 	query := `
-		INSERT INTO todos (user_id, title, description)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at, updated_at, completed
+		INSERT INTO todos (workspace_id, user_id, project_id, assignee_id, title, description, completed, state_id, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at, version, (SELECT name FROM workflow_states WHERE id = $8)
 	`
 
-	err := r.db.QueryRow(ctx, query, todo.UserID, todo.Title, todo.Description).
-		Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt, &todo.Completed)
+	todo.WorkspaceID = workspaceID
+	todo.BlockedBy = []int{}
+
+	// New todos go to the end of the manual order
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock_shared(hashtext('todo_positions'), $1)`, workspaceID); err != nil {
+		return err
+	}
+	var last string
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(position), '') FROM todos WHERE workspace_id = $1`, workspaceID).Scan(&last); err != nil {
+		return err
+	}
+	position, err := positionBetween(last, "")
+	if err != nil {
+		return err
+	}
+	todo.Position = position
 
-	return err
+	return tx.QueryRow(ctx, query, workspaceID, todo.UserID, todo.ProjectID, todo.AssigneeID, todo.Title, todo.Description, todo.Completed, todo.StateID, position).
+		Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt, &todo.Version, &todo.State)
 }
 
-func (r *TodoRepository) GetByID(ctx context.Context, id int) (*models.Todo, error) {
+// GetByID returns the todo only if userID has some role on it, and reports that role.
+func (r *TodoRepository) GetByID(ctx context.Context, id, userID int) (*models.Todo, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT id, user_id, title, description, completed, created_at, updated_at
-		FROM todos
-		WHERE id = $1
+		SELECT ` + todoColumns + `
+		FROM todos t
+		JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $2
+		WHERE t.id = $1 AND t.workspace_id = $3 AND t.deleted_at IS NULL
 	`
 
 	todo := &models.Todo{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&todo.ID,
-		&todo.UserID,
-		&todo.Title,
-		&todo.Description,
-		&todo.Completed,
-		&todo.CreatedAt,
-		&todo.UpdatedAt,
-	)
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return scanTodo(tx.QueryRow(ctx, query, id, userID, workspaceID), todo)
+	})
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -61,121 +149,707 @@ func (r *TodoRepository) GetByID(ctx context.Context, id int) (*models.Todo, err
 	return todo, nil
 }
 
-func (r *TodoRepository) GetByUserID(ctx context.Context, userID, page, limit int, status, sortBy string) ([]models.Todo, int, error) {
+// todoListQuery is a todo listing before ordering and pagination.
+type todoListQuery struct {
+	sql  string
+	args []interface{}
+	// ranked listings are searches; they select a rank and two snippets after comment_count
+	ranked bool
+	// empty is set when the options can't match anything, so there's nothing to run
+	empty bool
+}
+
+// buildTodoListQuery turns listing options into a query over the todos userID can see.
+// $1 is always the user and $2 the workspace.
+func buildTodoListQuery(userID, workspaceID int, opts models.TodoListOptions) (*todoListQuery, error) {
+	fuzzy := opts.SearchMode == models.SearchModeFuzzy
+
+	// A full-text search without a single word can't match anything
+	tsQuery := prefixTSQuery(opts.Query)
+	if opts.Query != "" && !fuzzy && tsQuery == "" {
+		return &todoListQuery{empty: true}, nil
+	}
+
 	// 1. Build the base query and arguments
 	var queryBuilder strings.Builder
 	args := make([]interface{}, 0, 5) // Create a slice to hold our query arguments
+	args = append(args, userID, workspaceID)
+	argCounter := 3 // $1 is used for userID, $2 for the workspace
 
-	// Start with the base query for selecting todos
-	queryBuilder.WriteString("SELECT id, user_id, title, description, completed, created_at, updated_at FROM todos WHERE user_id = $1")
-	args = append(args, userID)
-	argCounter := 2 // $1 is used for userID
+	// Start with the base query for selecting todos the user can see
+	queryBuilder.WriteString("SELECT " + todoColumns + ", " +
+		"(SELECT COUNT(*) FROM todo_comments c WHERE c.todo_id = t.id AND c.deleted_at IS NULL) AS comment_count")
 
-	// 2. Add filters (status)
-	if status == "completed" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND completed = $%d", argCounter))
+	// Searches also return the relevance and snippets, HTML-escaped: highlighted
+	// ones for full-text search, the whole text for fuzzy search (trigrams have
+	// no notion of words to mark). Full-text search parses the query with each
+	// todo's own text search configuration, the one its search vector was built with
+	var match string
+	title, description := htmlEscapeSQL("t.title"), htmlEscapeSQL("COALESCE(t.description, '')")
+	switch {
+	case opts.Query != "" && fuzzy:
+		queryBuilder.WriteString(fmt.Sprintf(", GREATEST(word_similarity($%[1]d, t.title), word_similarity($%[1]d, COALESCE(t.description, ''))) AS rank"+
+			", %[2]s AS title_snippet, %[3]s AS description_snippet", argCounter, title, description))
+		match = fmt.Sprintf("($%[1]d <%% t.title OR $%[1]d <%% t.description)", argCounter)
+		args = append(args, opts.Query)
+		argCounter++
+	case opts.Query != "":
+		search := fmt.Sprintf("to_tsquery(t.search_language, $%d)", argCounter)
+		queryBuilder.WriteString(fmt.Sprintf(", ts_rank_cd(t.search_vector, %[1]s) AS rank"+
+			", ts_headline(t.search_language, %[3]s, %[1]s, 'HighlightAll=true, %[2]s') AS title_snippet"+
+			", ts_headline(t.search_language, %[4]s, %[1]s, '%[2]s') AS description_snippet",
+			search, headlineOptions, title, description))
+		match = "t.search_vector @@ " + search
+		args = append(args, tsQuery)
+		argCounter++
+	}
+
+	queryBuilder.WriteString(" FROM todos t JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $1 WHERE t.workspace_id = $2 AND t.deleted_at IS NULL")
+	if match != "" {
+		queryBuilder.WriteString(" AND " + match)
+	}
+
+	// 2. Add the scope (whose todos) and filters (status). Scope goes by who
+	// owns the todo, not the role: a todo shared with the user as owner is
+	// still someone else's
+	switch opts.Scope {
+	case models.ScopeShared:
+		queryBuilder.WriteString(" AND t.user_id <> $1")
+	case models.ScopeAll:
+		// Anything the access view returned
+	default:
+		queryBuilder.WriteString(" AND t.user_id = $1")
+	}
+
+	// Archived todos are out of sight unless asked for
+	if opts.Status == models.StatusArchived {
+		queryBuilder.WriteString(" AND t.archived_at IS NOT NULL")
+	} else {
+		queryBuilder.WriteString(" AND t.archived_at IS NULL")
+	}
+
+	if opts.Status == models.StatusCompleted {
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.completed = $%d", argCounter))
 		args = append(args, true)
 		argCounter++
-	} else if status == "pending" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND completed = $%d", argCounter))
+	} else if opts.Status == models.StatusPending {
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.completed = $%d", argCounter))
 		args = append(args, false)
 		argCounter++
+	} else if opts.Status != "" && opts.Status != models.StatusArchived {
+		// Any other status names a workflow state, in whichever projects have one by that name
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.state_id IN (SELECT id FROM workflow_states WHERE lower(name) = lower($%d))", argCounter))
+		args = append(args, opts.Status)
+		argCounter++
+	}
+
+	if opts.ProjectID != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.project_id = $%d", argCounter))
+		args = append(args, *opts.ProjectID)
+		argCounter++
+	}
+	if opts.StateID != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.state_id = $%d", argCounter))
+		args = append(args, *opts.StateID)
+		argCounter++
+	}
+
+	if opts.Actionable {
+		queryBuilder.WriteString(" AND NOT t.completed AND NOT " + todoBlockedSQL)
+	}
+
+	if opts.Unassigned {
+		queryBuilder.WriteString(" AND t.assignee_id IS NULL")
+	} else if opts.AssigneeID != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.assignee_id = $%d", argCounter))
+		args = append(args, *opts.AssigneeID)
+		argCounter++
+	}
+
+	if opts.Filter != nil {
+		condition, filterArgs, err := compileTodoFilter(opts.Filter, userID, time.Now().UTC(), args)
+		if err != nil {
+			return nil, err
+		}
+		queryBuilder.WriteString(" AND " + condition)
+		args = filterArgs
+	}
+
+	return &todoListQuery{sql: queryBuilder.String(), args: args, ranked: match != ""}, nil
+}
+
+// GetByUserID returns one page of a listing, found either by page number
+// (OFFSET) or, in cursor mode, by keyset: the rows after (or before) the
+// cursor's sort keys. Keyset pages stay correct while todos are being added and
+// don't get slower further in, so cursor mode only counts the total on request.
+func (r *TodoRepository) GetByUserID(ctx context.Context, userID int, opts models.TodoListOptions) (*models.TodoPage, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := buildTodoListQuery(userID, workspaceID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := todoSortKeys(opts, list.ranked)
+	if err != nil {
+		return nil, err
+	}
+	var cursorValues []any
+	var backwards bool
+	if opts.Cursor != "" {
+		if cursorValues, backwards, err = decodeTodoCursor(opts.Cursor, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	page := &models.TodoPage{Todos: []models.Todo{}}
+	if list.empty {
+		if !opts.CursorMode || opts.IncludeTotal {
+			page.Total = new(int)
+		}
+		return page, nil
 	}
 
 	// 3. Get the Total Count *with* the filters applied
 	// This is crucial for pagination. We run a COUNT on the filtered query.
-	countQuery := "SELECT COUNT(*) FROM (" + queryBuilder.String() + ") AS filtered_todos"
-	var total int
-	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		log.Printf("Error counting todos: %v", err)
-		return nil, 0, err
-	}
-
-	// 4. Add Sorting
-	// We MUST whitelist sort_by values to prevent SQL injection.
-	orderBy := "ORDER BY created_at DESC" // Default sort
-	switch sortBy {
-	case "title":
-		orderBy = "ORDER BY title ASC"
-	case "updated_at":
-		orderBy = "ORDER BY updated_at DESC"
-	}
-	queryBuilder.WriteString(" " + orderBy) // It's safe to add this because it's from our whitelist
-
-	// 5. Add Pagination
-	offset := (page - 1) * limit
-	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCounter, argCounter+1))
-	args = append(args, limit, offset)
-
-	// 6. Execute the final, dynamic query
-	finalQuery := queryBuilder.String()
-	rows, err := r.db.Query(ctx, finalQuery, args...)
-	if err != nil {
-		log.Printf("Error querying todos: %v, query: %s", err, finalQuery)
-		return nil, 0, err
-	}
-	defer rows.Close()
-
-	// 7. Scan the results (no change from before)
-	todos := []models.Todo{}
-	for rows.Next() {
-		var todo models.Todo
-		err := rows.Scan(
-			&todo.ID,
-			&todo.UserID,
-			&todo.Title,
-			&todo.Description,
-			&todo.Completed,
-			&todo.CreatedAt,
-			&todo.UpdatedAt,
-		)
+	countQuery := "SELECT COUNT(*) FROM (" + list.sql + ") AS filtered_todos"
+
+	// 4. Add Sorting and Pagination. The listing becomes a subquery so the sort
+	// keys (rank included) can be referred to by name in the keyset condition.
+	// Walking backwards reads the rows before the cursor in reverse and flips them after.
+	// One extra row tells whether there is a further page.
+	args := append([]interface{}{}, list.args...)
+	finalQuery := "SELECT * FROM (" + list.sql + ") AS listed"
+	if backwards {
+		for i := range keys {
+			keys[i] = keys[i].reversed()
+		}
+	}
+	if cursorValues != nil {
+		var condition string
+		condition, args = keysetCondition(keys, cursorValues, args)
+		finalQuery += " WHERE " + condition
+	}
+	finalQuery += " " + orderByClause(keys) // It's safe to add this because it's from our whitelist
+
+	args = append(args, opts.Limit+1)
+	finalQuery += fmt.Sprintf(" LIMIT $%d", len(args))
+	if !opts.CursorMode {
+		args = append(args, (opts.Page-1)*opts.Limit)
+		finalQuery += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	// 5. Execute the count and the final, dynamic query in the user's scope
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		if !opts.CursorMode || opts.IncludeTotal {
+			page.Total = new(int)
+			if err := tx.QueryRow(ctx, countQuery, list.args...).Scan(page.Total); err != nil {
+				log.Printf("Error counting todos: %v", err)
+				return err
+			}
+		}
+
+		rows, err := tx.Query(ctx, finalQuery, args...)
 		if err != nil {
-			return nil, 0, err
+			log.Printf("Error querying todos: %v, query: %s", err, finalQuery)
+			return err
+		}
+		defer rows.Close()
+
+		// 6. Scan the results
+		for rows.Next() {
+			var todo models.Todo
+			extra := []any{&todo.CommentCount}
+			if list.ranked {
+				todo.Search = &models.TodoSearchMatch{}
+				extra = append(extra, &todo.Search.Rank, &todo.Search.Title, &todo.Search.Description)
+			}
+			if err := scanTodo(rows, &todo, extra...); err != nil {
+				return err
+			}
+			page.Todos = append(page.Todos, todo)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 7. Work out the cursors around this page
+	more := len(page.Todos) > opts.Limit
+	if more {
+		page.Todos = page.Todos[:opts.Limit]
+	}
+	if backwards {
+		slices.Reverse(page.Todos)
+		for i := range keys {
+			keys[i] = keys[i].reversed()
 		}
-		todos = append(todos, todo)
+	}
+	if len(page.Todos) == 0 {
+		return page, nil
 	}
 
-	return todos, total, nil
+	// Going forwards there's a next page if the extra row showed up, and a previous
+	// one if we came from a cursor; going backwards it's the other way round
+	hasNext, hasPrev := more, cursorValues != nil
+	if backwards {
+		hasNext, hasPrev = cursorValues != nil, more
+	}
+	if hasNext {
+		if page.NextCursor, err = encodeTodoCursor(keys, &page.Todos[len(page.Todos)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev && opts.CursorMode {
+		if page.PrevCursor, err = encodeTodoCursor(keys, &page.Todos[0], true); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
 }
 
-func (r *TodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+// CountByUserID counts the todos matching each of the listings, in one round of queries.
+func (r *TodoRepository) CountByUserID(ctx context.Context, userID int, opts []models.TodoListOptions) ([]int, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]*todoListQuery, len(opts))
+	for i := range opts {
+		if lists[i], err = buildTodoListQuery(userID, workspaceID, opts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	counts := make([]int, len(opts))
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for i, list := range lists {
+			if list.empty {
+				continue
+			}
+			batch.Queue("SELECT COUNT(*) FROM ("+list.sql+") AS filtered_todos", list.args...).
+				QueryRow(func(row pgx.Row) error {
+					return row.Scan(&counts[i])
+				})
+		}
+		if batch.Len() == 0 {
+			return nil
+		}
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// Title similarity (pg_trgm, 0 to 1) from which todos count as similar, and as likely duplicates
+const (
+	similarTodoThreshold   = 0.3
+	duplicateTodoThreshold = 0.6
+)
+
+// FindSimilar returns up to limit other todos the user can see whose titles
+// resemble the todo's, most similar first.
+func (r *TodoRepository) FindSimilar(ctx context.Context, todo *models.Todo, userID, limit int) ([]models.SimilarTodo, error) {
+	return r.findByTitleSimilarity(ctx, userID, todo.Title, todo.ID, similarTodoThreshold, false, limit)
+}
+
+// FindDuplicates returns open todos the user can see whose titles are
+// near-identical to title, for warning about a todo that is about to be created.
+func (r *TodoRepository) FindDuplicates(ctx context.Context, title string, userID int) ([]models.SimilarTodo, error) {
+	return r.findByTitleSimilarity(ctx, userID, title, 0, duplicateTodoThreshold, true, 5)
+}
+
+func (r *TodoRepository) findByTitleSimilarity(ctx context.Context, userID int, title string, excludeID int, threshold float64, openOnly bool, limit int) ([]models.SimilarTodo, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The % operator lets the trigram index find candidates; the explicit
+	// comparison applies our own threshold instead of pg_trgm's setting
 	query := `
-		UPDATE todos
-		SET title = $1, description = $2, completed = $3, updated_at = NOW()
-		WHERE id = $4 AND user_id = $5
-		RETURNING updated_at
+		SELECT ` + todoColumns + `, similarity(t.title, $3) AS sim
+		FROM todos t
+		JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $1
+		WHERE t.workspace_id = $2 AND t.deleted_at IS NULL
+		  AND t.title % $3 AND similarity(t.title, $3) >= $4
+		  AND t.id <> $5
+		  AND (NOT $6 OR NOT t.completed)
+		ORDER BY sim DESC, t.id ASC
+		LIMIT $7
 	`
 
-	err := r.db.QueryRow(ctx, query,
+	similar := []models.SimilarTodo{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, userID, workspaceID, title, threshold, excludeID, openOnly, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var s models.SimilarTodo
+			if err := scanTodo(rows, &s.Todo, &s.Similarity); err != nil {
+				return err
+			}
+			similar = append(similar, s)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return similar, nil
+}
+
+// CanBeAssigned reports whether assigneeID may be assigned the todo as it is about to be
+// saved: they must be a member of the active workspace and either own the todo, have
+// accepted a share of it, or have access to its project. actorID is the user making the change.
+func (r *TodoRepository) CanBeAssigned(ctx context.Context, todo *models.Todo, assigneeID, actorID int) (bool, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM workspace_members
+			WHERE workspace_id = $4 AND user_id = $3
+		) AND (
+			$3 = $5
+			OR EXISTS (
+				SELECT 1 FROM shares
+				WHERE todo_id = $1 AND user_id = $3 AND status = 'accepted'
+			) OR EXISTS (
+				SELECT 1 FROM project_access
+				WHERE project_id = $2 AND user_id = $3
+			)
+		)
+	`
+
+	var ok bool
+	err = withUserScope(ctx, r.db, actorID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, todo.ID, todo.ProjectID, assigneeID, workspaceID, todo.UserID).Scan(&ok)
+	})
+	return ok, err
+}
+
+// Update saves the todo if userID is at least an editor on it and it is still
+// at todo.Version; otherwise someone changed it since it was read and Update
+// returns ErrVersionConflict. On success todo.Version is the new version.
+func (r *TodoRepository) Update(ctx context.Context, todo *models.Todo, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return updateTodo(ctx, tx, workspaceID, todo, userID)
+	})
+}
+
+var (
+	// ErrVersionConflict refuses a write based on a version of the todo that is no longer current
+	ErrVersionConflict = errors.New("the todo was changed by someone else")
+
+	// errTodoNotFound is returned by writes that found no todo the user may change.
+	errTodoNotFound = errors.New("todo not found or unauthorized")
+)
+
+// writeMissed explains why a write to todo id matched no row: either userID
+// can't make it at all, or the todo is at another version than expected.
+func writeMissed(ctx context.Context, tx pgx.Tx, workspaceID, id, userID int, role string) error {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM todos t
+			JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $2 AND a.role_rank >= $3
+			WHERE t.id = $1 AND t.workspace_id = $4 AND t.deleted_at IS NULL
+		)
+	`, id, userID, models.RoleRank(role), workspaceID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return errTodoNotFound
+}
+
+func updateTodo(ctx context.Context, tx pgx.Tx, workspaceID int, todo *models.Todo, userID int) error {
+	query := `
+		UPDATE todos t
+		SET title = $1, description = $2, completed = $3, project_id = $4, assignee_id = $5, state_id = $10, updated_at = NOW()
+		WHERE t.id = $6 AND t.workspace_id = $9 AND t.version = $11 AND t.deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $7 AND a.role_rank >= $8
+		)
+		RETURNING updated_at, version, (SELECT name FROM workflow_states WHERE id = $10), ` + todoBlockersSQL + `
+	`
+
+	err := tx.QueryRow(ctx, query,
 		todo.Title,
 		todo.Description,
 		todo.Completed,
+		todo.ProjectID,
+		todo.AssigneeID,
 		todo.ID,
-		todo.UserID,
-	).Scan(&todo.UpdatedAt)
+		userID,
+		models.RoleRank(models.RoleEditor),
+		workspaceID,
+		todo.StateID,
+		todo.Version,
+	).Scan(&todo.UpdatedAt, &todo.Version, &todo.State, &todo.BlockedBy)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return errors.New("todo not found or unauthorized")
+			return writeMissed(ctx, tx, workspaceID, todo.ID, userID, models.RoleEditor)
 		}
 		return err
 	}
 
+	todo.Blocked = len(todo.BlockedBy) > 0
 	return nil
 }
 
-func (r *TodoRepository) Delete(ctx context.Context, id, userID int) error {
-	query := `DELETE FROM todos WHERE id = $1 AND user_id = $2`
+// Delete moves the todo to the trash if userID is an owner of it and it is
+// still at todo.Version (ErrVersionConflict otherwise). Trashed todos are left
+// out of every other query here until they are restored; they are only
+// removed for good by EmptyTrash or PurgeTrash.
+func (r *TodoRepository) Delete(ctx context.Context, todo *models.Todo, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
 
-	result, err := r.db.Exec(ctx, query, id, userID)
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		version, err := trashTodo(ctx, tx, workspaceID, todo.ID, todo.Version, userID)
+		if err != nil {
+			return err
+		}
+		todo.Version = version
+		return nil
+	})
+}
+
+// trashTodo moves todo id to the trash and returns the version that leaves it
+// at. A version of 0 skips the version check.
+func trashTodo(ctx context.Context, tx pgx.Tx, workspaceID, id, version, userID int) (int, error) {
+	query := `
+		UPDATE todos t
+		SET deleted_at = NOW()
+		WHERE t.id = $1 AND t.workspace_id = $4 AND ($5 = 0 OR t.version = $5) AND t.deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $2 AND a.role_rank >= $3
+		)
+		RETURNING t.version
+	`
+
+	err := tx.QueryRow(ctx, query, id, userID, models.RoleRank(models.RoleOwner), workspaceID, version).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, writeMissed(ctx, tx, workspaceID, id, userID, models.RoleOwner)
+	}
+	return version, err
+}
+
+var ErrAnchorNotFound = errors.New("anchor todo not found")
+
+// errNeedsRebalance means the keys around the anchor leave no room for a move.
+var errNeedsRebalance = errors.New("positions need rebalancing")
+
+// Move places the todo right after the anchor todo, or right before it, by
+// giving it a key between the anchor and the anchor's neighbour. Moves in a
+// workspace are serialized with an advisory lock, so two devices moving todos
+// at once each see the other's result. userID must be an editor of the todo
+// and able to see the anchor.
+func (r *TodoRepository) Move(ctx context.Context, todo *models.Todo, anchorID int, after bool, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
 	if err != nil {
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return errors.New("todo not found or unauthorized")
+	err = r.move(ctx, workspaceID, todo, anchorID, after, userID)
+	if errors.Is(err, errNeedsRebalance) {
+		if err := r.rebalanceWorkspace(ctx, workspaceID); err != nil {
+			return err
+		}
+		err = r.move(ctx, workspaceID, todo, anchorID, after, userID)
+	}
+	if err != nil {
+		return err
 	}
 
+	if len(todo.Position) > maxPositionLength {
+		if err := r.rebalanceWorkspace(ctx, workspaceID); err != nil {
+			log.Printf("Error rebalancing todo positions in workspace %d: %v", workspaceID, err)
+		}
+	}
 	return nil
 }
+
+func (r *TodoRepository) move(ctx context.Context, workspaceID int, todo *models.Todo, anchorID int, after bool, userID int) error {
+	anchorQuery := `
+		SELECT t.position
+		FROM todos t
+		JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $2
+		WHERE t.id = $1 AND t.workspace_id = $3 AND t.deleted_at IS NULL
+	`
+
+	// The neighbour on the side the todo goes to, not counting the todo itself
+	neighbourQuery := `
+		SELECT position FROM todos
+		WHERE workspace_id = $1 AND id <> $2 AND position IS NOT NULL AND deleted_at IS NULL
+		  AND (position, id) < ($3, $4)
+		ORDER BY position DESC, id DESC
+		LIMIT 1
+	`
+	if after {
+		neighbourQuery = `
+			SELECT position FROM todos
+			WHERE workspace_id = $1 AND id <> $2 AND position IS NOT NULL AND deleted_at IS NULL
+			  AND (position, id) > ($3, $4)
+			ORDER BY position ASC, id ASC
+			LIMIT 1
+		`
+	}
+
+	updateQuery := `
+		UPDATE todos t
+		SET position = $1, updated_at = NOW()
+		WHERE t.id = $2 AND t.workspace_id = $5 AND t.deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $3 AND a.role_rank >= $4
+		)
+		RETURNING updated_at, version
+	`
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('todo_positions'), $1)`, workspaceID); err != nil {
+			return err
+		}
+
+		var anchor *string
+		if err := tx.QueryRow(ctx, anchorQuery, anchorID, userID, workspaceID).Scan(&anchor); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrAnchorNotFound
+			}
+			return err
+		}
+		if anchor == nil {
+			return errNeedsRebalance
+		}
+
+		var neighbour string
+		err := tx.QueryRow(ctx, neighbourQuery, workspaceID, todo.ID, *anchor, anchorID).Scan(&neighbour)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if neighbour == *anchor {
+			return errNeedsRebalance
+		}
+
+		lower, upper := neighbour, *anchor
+		if after {
+			lower, upper = *anchor, neighbour
+		}
+		position, err := positionBetween(lower, upper)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, updateQuery, position, todo.ID, userID, models.RoleRank(models.RoleEditor), workspaceID).
+			Scan(&todo.UpdatedAt, &todo.Version)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.New("todo not found or unauthorized")
+			}
+			return err
+		}
+
+		todo.Position = position
+		return nil
+	})
+}
+
+// RebalancePositions gives fresh keys to the todos of every workspace that has
+// todos without a key or with keys grown too long, and reports how many
+// workspaces it rebalanced. It runs as a background job.
+func (r *TodoRepository) RebalancePositions(ctx context.Context) (int, error) {
+	var workspaceIDs []int
+	err := withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT DISTINCT workspace_id FROM todos
+			WHERE position IS NULL OR length(position) > $1
+		`, maxPositionLength)
+		if err != nil {
+			return err
+		}
+		workspaceIDs, err = pgx.CollectRows(rows, pgx.RowTo[int])
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, workspaceID := range workspaceIDs {
+		if err := r.rebalanceWorkspace(ctx, workspaceID); err != nil {
+			return i, err
+		}
+	}
+	return len(workspaceIDs), nil
+}
+
+// rebalanceWorkspace rewrites the keys of all todos in the workspace as short,
+// evenly spaced ones, keeping their order. Todos without a key keep their
+// place after all others. It covers every user's todos, so it runs in the system scope.
+// The order doesn't change, so neither do the todos' versions (see migrations/000024).
+func (r *TodoRepository) rebalanceWorkspace(ctx context.Context, workspaceID int) error {
+	return withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('todo_positions'), $1)`, workspaceID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `SELECT set_config('app.rebalancing_positions', 'on', true)`); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT id FROM todos
+			WHERE workspace_id = $1
+			ORDER BY position ASC NULLS LAST, id ASC
+		`, workspaceID)
+		if err != nil {
+			return err
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		positions := make([]string, len(ids))
+		previous := ""
+		for i := range ids {
+			if positions[i], err = positionBetween(previous, ""); err != nil {
+				return err
+			}
+			previous = positions[i]
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE todos t SET position = v.position
+			FROM unnest($1::int[], $2::text[]) AS v(id, position)
+			WHERE t.id = v.id AND t.position IS DISTINCT FROM v.position
+		`, ids, positions)
+		return err
+	})
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/pigeio/todo-api/internal/filter"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+func TestBuildTodoListQueryNumbersEveryArgument(t *testing.T) {
+	expr, err := filter.Parse("title:plan", models.TodoFilterFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectID, stateID, assigneeID := 3, 4, 5
+	opts := models.TodoListOptions{
+		Query:      "plan",
+		Status:     models.StatusPending,
+		ProjectID:  &projectID,
+		StateID:    &stateID,
+		AssigneeID: &assigneeID,
+		Filter:     expr,
+	}
+
+	list, err := buildTodoListQuery(1, 2, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	used := map[int]bool{}
+	for _, m := range regexp.MustCompile(`\$(\d+)`).FindAllStringSubmatch(list.sql, -1) {
+		n, _ := strconv.Atoi(m[1])
+		used[n] = true
+	}
+	for n := 1; n <= len(list.args); n++ {
+		if !used[n] {
+			t.Errorf("$%d is never used", n)
+		}
+	}
+	if len(used) != len(list.args) {
+		t.Errorf("%d placeholders for %d arguments:\n%s\n%s", len(used), len(list.args), list.sql, fmt.Sprint(list.args...))
+	}
+}
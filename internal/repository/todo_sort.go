@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type sortKind int
+
+const (
+	sortTime sortKind = iota
+	sortText
+	sortRank
+	sortInt
+	sortBool
+)
+
+// sortKey is one ORDER BY term of a todo listing. Columns name the columns of
+// the listing subquery (see GetByUserID), not of the todos table.
+type sortKey struct {
+	column     string
+	desc       bool
+	nullsFirst bool
+	kind       sortKind
+	// value reads the key off a listed todo, for building cursors
+	value func(todo *models.Todo) any
+}
+
+func (k sortKey) orderBy() string {
+	sql := k.column + " ASC"
+	if k.desc {
+		sql = k.column + " DESC"
+	}
+	if k.nullsFirst {
+		return sql + " NULLS FIRST"
+	}
+	return sql + " NULLS LAST"
+}
+
+// reversed sorts the other way round, nulls included, for walking backwards.
+func (k sortKey) reversed() sortKey {
+	k.desc = !k.desc
+	k.nullsFirst = !k.nullsFirst
+	return k
+}
+
+var (
+	sortByID        = sortKey{column: "id", kind: sortInt, value: func(t *models.Todo) any { return t.ID }}
+	sortByCreatedAt = sortKey{column: "created_at", kind: sortTime, value: func(t *models.Todo) any { return t.CreatedAt }}
+	sortByUpdatedAt = sortKey{column: "updated_at", kind: sortTime, value: func(t *models.Todo) any { return t.UpdatedAt }}
+	sortByRank      = sortKey{column: "rank", kind: sortRank, value: func(t *models.Todo) any { return t.Search.Rank }}
+	// Titles sort by the todo_title collation (see migrations), which orders
+	// by language rules and compares embedded numbers naturally ("Step 2" < "Step 10")
+	sortByTitle     = sortKey{column: `(title COLLATE todo_title)`, kind: sortText, value: func(t *models.Todo) any { return t.Title }}
+	sortByCompleted = sortKey{column: "completed", kind: sortBool, value: func(t *models.Todo) any { return t.Completed }}
+	sortByProject   = sortKey{column: "project_id", kind: sortInt, value: func(t *models.Todo) any { return t.ProjectID }}
+	sortByPosition  = sortKey{column: "position", kind: sortText, value: func(t *models.Todo) any {
+		if t.Position == "" {
+			return nil // not placed yet
+		}
+		return t.Position
+	}}
+	sortByAssignee = sortKey{column: "assignee_id", kind: sortInt, value: func(t *models.Todo) any { return t.AssigneeID }}
+)
+
+// todoSortColumns maps models.TodoSortFields to sort keys.
+// We MUST whitelist sort fields to prevent SQL injection.
+var todoSortColumns = map[string]sortKey{
+	"id":         sortByID,
+	"created_at": sortByCreatedAt,
+	"updated_at": sortByUpdatedAt,
+	"rank":       sortByRank,
+	"title":      sortByTitle,
+	"completed":  sortByCompleted,
+	"project":    sortByProject,
+	"assignee":   sortByAssignee,
+	"position":   sortByPosition,
+}
+
+func descending(k sortKey) sortKey {
+	k.desc = true
+	return k
+}
+
+// todoSortKeys returns the ordering of a listing. It always ends in id (in the
+// direction of the first key), so todos with equal keys still have a stable
+// order and cursors are unambiguous.
+func todoSortKeys(opts models.TodoListOptions, ranked bool) ([]sortKey, error) {
+	if len(opts.Sort) == 0 {
+		if ranked {
+			return []sortKey{descending(sortByRank), descending(sortByCreatedAt), descending(sortByID)}, nil
+		}
+		return []sortKey{descending(sortByCreatedAt), descending(sortByID)}, nil // Default sort
+	}
+
+	keys := make([]sortKey, 0, len(opts.Sort)+1)
+	hasID := false
+	for _, field := range opts.Sort {
+		key, ok := todoSortColumns[field.Field]
+		if !ok || (key.kind == sortRank && !ranked) {
+			return nil, fmt.Errorf("cannot sort todos by %q", field.Field)
+		}
+		key.desc = field.Desc
+		key.nullsFirst = field.NullsFirst
+		keys = append(keys, key)
+		hasID = hasID || field.Field == "id"
+	}
+
+	if !hasID {
+		id := sortByID
+		id.desc = keys[0].desc
+		keys = append(keys, id)
+	}
+	return keys, nil
+}
+
+func orderByClause(keys []sortKey) string {
+	terms := make([]string, len(keys))
+	for i, k := range keys {
+		terms[i] = k.orderBy()
+	}
+	return "ORDER BY " + strings.Join(terms, ", ")
+}
+
+// sortSignature identifies an ordering, so a cursor can't be used with another one.
+func sortSignature(keys []sortKey) string {
+	return strings.TrimPrefix(orderByClause(keys), "ORDER BY ")
+}
+
+// todoCursor is the decoded form of the opaque next_cursor/prev_cursor values:
+// the sort keys of the todo the page starts after (or before, walking back).
+type todoCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+	Prev   bool              `json:"p,omitempty"`
+}
+
+func encodeTodoCursor(keys []sortKey, todo *models.Todo, prev bool) (string, error) {
+	cursor := todoCursor{Sort: sortSignature(keys), Prev: prev}
+	for _, k := range keys {
+		raw, err := json.Marshal(k.value(todo))
+		if err != nil {
+			return "", err
+		}
+		cursor.Values = append(cursor.Values, raw)
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeTodoCursor checks the cursor against the listing's ordering and
+// converts its values to the keys' types.
+func decodeTodoCursor(s string, keys []sortKey) (values []any, prev bool, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false, ErrInvalidCursor
+	}
+
+	var cursor todoCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, false, ErrInvalidCursor
+	}
+	if cursor.Sort != sortSignature(keys) || len(cursor.Values) != len(keys) {
+		return nil, false, ErrInvalidCursor
+	}
+
+	values = make([]any, len(keys))
+	for i, k := range keys {
+		if string(cursor.Values[i]) == "null" {
+			continue
+		}
+
+		var dest any
+		switch k.kind {
+		case sortTime:
+			dest = new(time.Time)
+		case sortText:
+			dest = new(string)
+		case sortRank:
+			dest = new(float32)
+		case sortInt:
+			dest = new(int)
+		case sortBool:
+			dest = new(bool)
+		}
+		if err := json.Unmarshal(cursor.Values[i], dest); err != nil {
+			return nil, false, ErrInvalidCursor
+		}
+
+		switch v := dest.(type) {
+		case *time.Time:
+			values[i] = *v
+		case *string:
+			values[i] = *v
+		case *float32:
+			values[i] = *v
+		case *int:
+			values[i] = *v
+		case *bool:
+			values[i] = *v
+		}
+	}
+
+	return values, cursor.Prev, nil
+}
+
+// keysetCondition matches the rows that come after values in the ordering keys:
+// those past the first key, or tied on it and past the second, and so on. Nulls
+// sort as the keys say. Values are appended to args and bound by placeholder.
+func keysetCondition(keys []sortKey, values []any, args []interface{}) (string, []interface{}) {
+	bind := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var alternatives []string
+	var tied []string
+	for i, k := range keys {
+		if after := keyAfter(k, values[i], bind); after != "" {
+			alternatives = append(alternatives, "("+strings.Join(append(append([]string{}, tied...), after), " AND ")+")")
+		}
+
+		if i == len(keys)-1 {
+			break
+		}
+		if values[i] == nil {
+			tied = append(tied, k.column+" IS NULL")
+		} else {
+			tied = append(tied, k.column+" = "+bind(values[i]))
+		}
+	}
+
+	if len(alternatives) == 0 {
+		return "FALSE", args
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// keyAfter matches values of a single key strictly after v, or returns "" if nothing can be.
+func keyAfter(k sortKey, v any, bind func(any) string) string {
+	if v == nil {
+		// Nulls come first: everything else is after them
+		if k.nullsFirst {
+			return k.column + " IS NOT NULL"
+		}
+		return ""
+	}
+
+	op := ">"
+	if k.desc {
+		op = "<"
+	}
+	after := k.column + " " + op + " " + bind(v)
+	if !k.nullsFirst {
+		after = "(" + after + " OR " + k.column + " IS NULL)"
+	}
+	return after
+}
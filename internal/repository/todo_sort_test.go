@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		keys     []sortKey
+		values   []any
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "descending with tiebreaker",
+			keys:     []sortKey{descending(sortByCreatedAt), descending(sortByID)},
+			values:   []any{created, 42},
+			wantSQL:  "(((created_at < $2 OR created_at IS NULL)) OR (created_at = $3 AND (id < $4 OR id IS NULL)))",
+			wantArgs: []any{created, created, 42},
+		},
+		{
+			name:     "null value with nulls last",
+			keys:     []sortKey{sortByProject, sortByID},
+			values:   []any{nil, 7},
+			wantSQL:  "((project_id IS NULL AND (id > $2 OR id IS NULL)))",
+			wantArgs: []any{7},
+		},
+		{
+			name:     "null value with nulls first",
+			keys:     []sortKey{sortByProject.reversed(), sortByID},
+			values:   []any{nil, 7},
+			wantSQL:  "((project_id IS NOT NULL) OR (project_id IS NULL AND (id > $2 OR id IS NULL)))",
+			wantArgs: []any{7},
+		},
+	}
+
+	for _, tt := range tests {
+		sql, args := keysetCondition(tt.keys, tt.values, []interface{}{"user"})
+		if sql != tt.wantSQL {
+			t.Errorf("%s: SQL =\n%s\nwant\n%s", tt.name, sql, tt.wantSQL)
+		}
+		if !reflect.DeepEqual(args[1:], tt.wantArgs) {
+			t.Errorf("%s: args = %v, want %v", tt.name, args[1:], tt.wantArgs)
+		}
+	}
+}
+
+func TestTodoCursorRoundTrip(t *testing.T) {
+	keys, _ := todoSortKeys(models.TodoListOptions{Query: "milk"}, true)
+	todo := &models.Todo{
+		ID:        9,
+		CreatedAt: time.Date(2026, 10, 1, 12, 0, 0, 123456000, time.UTC),
+		Search:    &models.TodoSearchMatch{Rank: 0.1},
+	}
+
+	cursor, err := encodeTodoCursor(keys, todo, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, prev, err := decodeTodoCursor(cursor, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{float32(0.1), todo.CreatedAt, 9}
+	if !prev || !reflect.DeepEqual(values, want) {
+		t.Errorf("decoded %v (prev %v), want %v (prev true)", values, prev, want)
+	}
+
+	// A cursor only works with the ordering it was issued for
+	other, _ := todoSortKeys(models.TodoListOptions{Sort: []models.SortField{{Field: "title"}}}, false)
+	if _, _, err := decodeTodoCursor(cursor, other); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("decoding with another sort: err = %v, want ErrInvalidCursor", err)
+	}
+	if _, _, err := decodeTodoCursor("not a cursor", keys); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("decoding garbage: err = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestTodoSortKeys(t *testing.T) {
+	keys, err := todoSortKeys(models.TodoListOptions{Sort: []models.SortField{
+		{Field: "assignee", Desc: true, NullsFirst: true},
+		{Field: "title"},
+	}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "ORDER BY assignee_id DESC NULLS FIRST, (title COLLATE todo_title) ASC NULLS LAST, id DESC NULLS LAST"
+	if got := orderByClause(keys); got != want {
+		t.Errorf("orderByClause = %q, want %q", got, want)
+	}
+
+	if _, err := todoSortKeys(models.TodoListOptions{Sort: []models.SortField{{Field: "rank"}}}, false); err == nil {
+		t.Error("sorting by rank outside a search: want error")
+	}
+	if _, err := todoSortKeys(models.TodoListOptions{Sort: []models.SortField{{Field: "title; DROP TABLE todos"}}}, false); err == nil {
+		t.Error("sorting by an unknown field: want error")
+	}
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+var (
+	ErrInvalidSyncToken = errors.New("invalid sync token")
+	// ErrSyncTokenExpired means the token is older than the tombstones are
+	// kept, so deletions since may be lost; the client has to sync from scratch.
+	ErrSyncTokenExpired = errors.New("sync token expired")
+)
+
+// syncToken is the decoded form of a sync token. A sync is a round of pages:
+// Since is where the round started (see migrations/000021), empty for a full
+// sync, and Next where the following round will. Between pages, After is the
+// last todo ID sent.
+type syncToken struct {
+	Workspace int       `json:"w"`
+	Since     string    `json:"s,omitempty"`
+	SinceAt   time.Time `json:"sa"`
+	Next      string    `json:"n,omitempty"`
+	NextAt    time.Time `json:"na"`
+	After     int       `json:"a,omitempty"`
+}
+
+func encodeSyncToken(token syncToken) (string, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeSyncToken checks that the token belongs to the workspace and holds
+// transaction IDs, which end up in queries.
+func decodeSyncToken(s string, workspaceID int) (syncToken, error) {
+	var token syncToken
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, ErrInvalidSyncToken
+	}
+	if err := json.Unmarshal(data, &token); err != nil || token.Workspace != workspaceID {
+		return token, ErrInvalidSyncToken
+	}
+	for _, txid := range []string{token.Since, token.Next} {
+		if _, err := strconv.ParseUint(txid, 10, 64); txid != "" && err != nil {
+			return token, ErrInvalidSyncToken
+		}
+	}
+	if (token.Next == "") != (token.After == 0) {
+		return token, ErrInvalidSyncToken
+	}
+	return token, nil
+}
+
+// readSyncToken decodes a token, or starts a full sync for an empty one.
+func readSyncToken(s string, workspaceID int, notBefore time.Time) (syncToken, error) {
+	if s == "" {
+		return syncToken{Workspace: workspaceID}, nil
+	}
+	token, err := decodeSyncToken(s, workspaceID)
+	if err != nil {
+		return token, err
+	}
+	if token.Since != "" && token.SinceAt.Before(notBefore) {
+		return token, ErrSyncTokenExpired
+	}
+	return token, nil
+}
+
+// CheckSyncToken returns the error SyncChanges would for the token, so a sync
+// can be refused before the client's changes are applied.
+func (r *TodoRepository) CheckSyncToken(ctx context.Context, s string, notBefore time.Time) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = readSyncToken(s, workspaceID, notBefore)
+	return err
+}
+
+// SyncChanges returns a page of up to limit todos userID can see that changed
+// since the token, in ID order, with the ones that went to the trash or were
+// deleted as tombstones, and the token to continue with. An empty token
+// starts a full sync: every todo, no tombstones. Tokens from a round that
+// started before notBefore are refused with ErrSyncTokenExpired.
+//
+// Todos the user loses access to without them changing (an unshare, say)
+// aren't reported; a full sync drops them.
+func (r *TodoRepository) SyncChanges(ctx context.Context, s string, notBefore time.Time, limit, userID int) (*models.SyncPage, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := readSyncToken(s, workspaceID, notBefore)
+	if err != nil {
+		return nil, err
+	}
+	full := token.Since == ""
+
+	// A full sync leaves out the trash; a delta sends what went there as tombstones
+	changed := `t.changed_txid >= $5::text::xid8`
+	args := []any{userID, workspaceID, token.After, limit + 1, token.Since}
+	if full {
+		changed = `t.deleted_at IS NULL`
+		args = args[:4]
+	}
+	query := `
+		SELECT ` + todoColumns + `, t.deleted_at
+		FROM todos t
+		JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $1
+		WHERE t.workspace_id = $2 AND t.id > $3 AND ` + changed + `
+		ORDER BY t.id
+		LIMIT $4
+	`
+
+	page := &models.SyncPage{Todos: []models.Todo{}, Deleted: []models.SyncTombstone{}, Full: full}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		// A round starts by taking where the next one will: anything that
+		// commits from now on has at least this transaction ID
+		if token.Next == "" {
+			err := tx.QueryRow(ctx, `SELECT pg_snapshot_xmin(pg_current_snapshot())::text, NOW()`).
+				Scan(&token.Next, &token.NextAt)
+			if err != nil {
+				return err
+			}
+
+			if !full {
+				rows, err := tx.Query(ctx, `
+					SELECT todo_id, deleted_at FROM todo_tombstones
+					WHERE workspace_id = $1 AND deleted_txid >= $2::text::xid8
+					ORDER BY todo_id
+				`, workspaceID, token.Since)
+				if err != nil {
+					return err
+				}
+				page.Deleted, err = pgx.CollectRows(rows, pgx.RowToStructByPos[models.SyncTombstone])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for n := 0; rows.Next(); n++ {
+			if n == limit {
+				page.HasMore = true
+				break
+			}
+			var todo models.Todo
+			if err := scanTodo(rows, &todo, &todo.DeletedAt); err != nil {
+				return err
+			}
+			token.After = todo.ID
+			if todo.DeletedAt != nil {
+				page.Deleted = append(page.Deleted, models.SyncTombstone{ID: todo.ID, DeletedAt: *todo.DeletedAt})
+			} else {
+				page.Todos = append(page.Todos, todo)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	next := syncToken{Workspace: workspaceID, Since: token.Next, SinceAt: token.NextAt}
+	if page.HasMore {
+		next = token
+	}
+	if page.SyncToken, err = encodeSyncToken(next); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// FieldChangesSince returns, for each todo of bases (todo ID to version), the
+// fields its revisions after that version changed, oldest first. Todos
+// userID can't see are left out.
+func (r *TodoRepository) FieldChangesSince(ctx context.Context, bases map[int]int, userID int) (map[int][]models.TodoFieldChange, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(bases))
+	versions := make([]int, 0, len(bases))
+	for id, version := range bases {
+		ids = append(ids, id)
+		versions = append(versions, version)
+	}
+
+	query := `
+		SELECT r.todo_id, c.change ->> 'field', r.version, r.created_at
+		FROM todo_revisions r
+		JOIN unnest($1::int[], $2::int[]) AS b(todo_id, version) ON b.todo_id = r.todo_id
+		CROSS JOIN LATERAL jsonb_array_elements(r.changes) AS c(change)
+		WHERE r.workspace_id = $3 AND r.version > b.version
+		ORDER BY r.todo_id, r.revision
+	`
+
+	changes := map[int][]models.TodoFieldChange{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, ids, versions, workspaceID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var todoID int
+			var change models.TodoFieldChange
+			if err := rows.Scan(&todoID, &change.Field, &change.Version, &change.ChangedAt); err != nil {
+				return err
+			}
+			changes[todoID] = append(changes[todoID], change)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// PurgeTombstones deletes the tombstones of todos deleted before the cutoff
+// and reports how many there were. It runs as a background job.
+func (r *TodoRepository) PurgeTombstones(ctx context.Context, before time.Time) (int, error) {
+	var purged int
+	err := withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM todo_tombstones WHERE deleted_at < $1`, before)
+		purged = int(result.RowsAffected())
+		return err
+	})
+	return purged, err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadSyncToken(t *testing.T) {
+	issued := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	encode := func(token syncToken) string {
+		s, err := encodeSyncToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		notBefore time.Time
+		want      error
+	}{
+		{"empty starts a full sync", "", issued, nil},
+		{"delta", encode(syncToken{Workspace: 7, Since: "1234", SinceAt: issued}), issued, nil},
+		{"page of a full sync", encode(syncToken{Workspace: 7, Next: "1234", NextAt: issued, After: 40}), issued.Add(time.Hour), nil},
+		{"expired", encode(syncToken{Workspace: 7, Since: "1234", SinceAt: issued}), issued.Add(time.Second), ErrSyncTokenExpired},
+		{"other workspace", encode(syncToken{Workspace: 8, Since: "1234", SinceAt: issued}), issued, ErrInvalidSyncToken},
+		{"not a transaction ID", encode(syncToken{Workspace: 7, Since: "1; DROP TABLE todos", SinceAt: issued}), issued, ErrInvalidSyncToken},
+		{"page without where it is", encode(syncToken{Workspace: 7, Since: "1234", SinceAt: issued, After: 40}), issued, ErrInvalidSyncToken},
+		{"garbage", "not a token", issued, ErrInvalidSyncToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readSyncToken(tt.token, 7, tt.notBefore); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
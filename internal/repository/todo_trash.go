@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+// The trash holds the todos a user deleted (see TodoRepository.Delete). Only
+// owners can delete todos, so a user's trash is the trashed todos they own.
+
+// ListTrash returns a page of the user's trashed todos, most recently deleted first.
+func (r *TodoRepository) ListTrash(ctx context.Context, userID, page, limit int) ([]models.Todo, int, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where := `
+		FROM todos t
+		JOIN todo_access a ON a.todo_id = t.id AND a.user_id = $1 AND a.role_rank >= $3
+		WHERE t.workspace_id = $2 AND t.deleted_at IS NOT NULL
+	`
+	query := `
+		SELECT ` + todoColumns + `, t.deleted_at
+		` + where + `
+		ORDER BY t.deleted_at DESC, t.id DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	todos := []models.Todo{}
+	var total int
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		ownerRank := models.RoleRank(models.RoleOwner)
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) "+where, userID, workspaceID, ownerRank).Scan(&total); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, userID, workspaceID, ownerRank, limit, (page-1)*limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var todo models.Todo
+			if err := scanTodo(rows, &todo, &todo.DeletedAt); err != nil {
+				return err
+			}
+			todos = append(todos, todo)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return todos, total, nil
+}
+
+// Restore takes the todo out of the trash if userID is an owner of it.
+func (r *TodoRepository) Restore(ctx context.Context, id, userID int) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE todos t
+		SET deleted_at = NULL
+		WHERE t.id = $1 AND t.workspace_id = $4 AND t.deleted_at IS NOT NULL AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $2 AND a.role_rank >= $3
+		)
+	`
+
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, id, userID, models.RoleRank(models.RoleOwner), workspaceID)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return errors.New("todo not found in trash")
+		}
+
+		return nil
+	})
+}
+
+// EmptyTrash permanently deletes the user's trashed todos and reports how many there were.
+func (r *TodoRepository) EmptyTrash(ctx context.Context, userID int) (int, error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		DELETE FROM todos t
+		WHERE t.workspace_id = $3 AND t.deleted_at IS NOT NULL AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $1 AND a.role_rank >= $2
+		)
+	`
+
+	var purged int
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, userID, models.RoleRank(models.RoleOwner), workspaceID)
+		purged = int(result.RowsAffected())
+		return err
+	})
+	return purged, err
+}
+
+// PurgeTrash permanently deletes every todo trashed before the cutoff, across
+// all users, and reports how many it deleted. It runs as a background job.
+func (r *TodoRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	var purged int
+	err := withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM todos WHERE deleted_at < $1`, before)
+		purged = int(result.RowsAffected())
+		return err
+	})
+	return purged, err
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+	"github.com/pigeio/todo-api/internal/tenant"
+)
+
+// ErrUndoNotFound covers tokens that don't exist, belong to someone else,
+// have expired or were already used; the caller can't tell which.
+var ErrUndoNotFound = errors.New("undo token not found, expired or already used")
+
+type UndoRepository struct {
+	db *pgxpool.Pool
+	// systemDB may bypass row-level security; only withSystemScope uses it
+	systemDB *pgxpool.Pool
+}
+
+func NewUndoRepository(db, systemDB *pgxpool.Pool) *UndoRepository {
+	return &UndoRepository{db: db, systemDB: systemDB}
+}
+
+func (r *UndoRepository) Create(ctx context.Context, undo *models.Undo) error {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO undo_tokens (token_hash, user_id, workspace_id, action, todos, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	undo.WorkspaceID = workspaceID
+	return withUserScope(ctx, r.db, undo.UserID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, undo.TokenHash, undo.UserID, workspaceID, undo.Action, undo.Todos, undo.ExpiresAt).
+			Scan(&undo.ID)
+	})
+}
+
+// Redeem uses up the token and puts its todos back the way they were, taking
+// them out of the trash if need be. It all happens in one transaction, so a
+// token is never half redeemed or redeemed twice. Todos the change created go
+// back to the trash. A todo is skipped if it changed again after the change
+// (its version moved on), if userID can no longer edit it, or own it when it
+// has to come out of the trash, or if it was purged; a project, state or
+// assignee that no longer exists is left empty. It returns the IDs of the
+// restored todos and of the skipped ones.
+func (r *UndoRepository) Redeem(ctx context.Context, tokenHash string, userID int) (restored, skipped []int, err error) {
+	workspaceID, err := tenant.WorkspaceID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redeemQuery := `
+		UPDATE undo_tokens
+		SET redeemed_at = NOW()
+		WHERE token_hash = $1 AND user_id = $2 AND workspace_id = $3
+		  AND redeemed_at IS NULL AND expires_at > NOW()
+		RETURNING todos
+	`
+
+	restoreQuery := `
+		UPDATE todos t
+		SET title = $2, description = $3, completed = $4,
+		    project_id = (SELECT id FROM projects WHERE id = $5),
+		    assignee_id = (SELECT id FROM users WHERE id = $6),
+		    state_id = (SELECT id FROM workflow_states WHERE id = $7 AND project_id = $5),
+		    position = $8, archived_at = $9, deleted_at = NULL, updated_at = NOW()
+		WHERE t.id = $1 AND t.workspace_id = $10 AND t.version = $14 AND EXISTS (
+			SELECT 1 FROM todo_access a
+			WHERE a.todo_id = t.id AND a.user_id = $11
+			  AND a.role_rank >= CASE WHEN t.deleted_at IS NULL THEN $12 ELSE $13 END
+		)
+	`
+
+	restored, skipped = []int{}, []int{}
+	err = withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		var snapshots []models.UndoSnapshot
+		if err := tx.QueryRow(ctx, redeemQuery, tokenHash, userID, workspaceID).Scan(&snapshots); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrUndoNotFound
+			}
+			return err
+		}
+
+		for _, s := range snapshots {
+			if s.Created {
+				_, err := trashTodo(ctx, tx, workspaceID, s.TodoID, s.Version, userID)
+				switch {
+				case err == nil:
+					restored = append(restored, s.TodoID)
+				case errors.Is(err, errTodoNotFound), errors.Is(err, ErrVersionConflict):
+					skipped = append(skipped, s.TodoID)
+				default:
+					return err
+				}
+				continue
+			}
+
+			result, err := tx.Exec(ctx, restoreQuery,
+				s.TodoID, s.Title, s.Description, s.Completed, s.ProjectID, s.AssigneeID, s.StateID,
+				nullIfEmpty(s.Position), s.ArchivedAt, workspaceID, userID,
+				models.RoleRank(models.RoleEditor), models.RoleRank(models.RoleOwner), s.Version)
+			if err != nil {
+				return err
+			}
+			if result.RowsAffected() > 0 {
+				restored = append(restored, s.TodoID)
+			} else {
+				skipped = append(skipped, s.TodoID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return restored, skipped, nil
+}
+
+// PurgeExpired deletes tokens that can no longer be redeemed and reports how
+// many there were. It runs as a background job.
+func (r *UndoRepository) PurgeExpired(ctx context.Context) (int, error) {
+	var purged int
+	err := withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM undo_tokens WHERE expires_at < NOW() OR redeemed_at IS NOT NULL`)
+		purged = int(result.RowsAffected())
+		return err
+	})
+	return purged, err
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
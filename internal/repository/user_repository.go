@@ -20,17 +20,31 @@ func NewUserRepository(db *pgxpool.Pool) User_Repository {
 	return &UserRepository{db: db}
 }
 
-// Create implements the User_Repository interface
+// Create implements the User_Repository interface.
+// Every user starts out with a personal workspace, created in the same transaction.
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
 		INSERT INTO users (name, email, password)
 		VALUES ($1, $2, $3)
 		RETURNING id, created_at
 	`
-	err := r.db.QueryRow(ctx, query, user.Name, user.Email, user.Password).
+	err = tx.QueryRow(ctx, query, user.Name, user.Email, user.Password).
 		Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := createPersonalWorkspace(ctx, tx, user); err != nil {
+		return err
+	}
 
-	return err
+	return tx.Commit(ctx)
 }
 
 // GetByEmail implements the User_Repository interface
@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+var (
+	ErrDuplicateStateName = errors.New("a state with this name already exists in the project")
+	// ErrLastState refuses changes that would leave a project without an open or without a done state
+	ErrLastState = errors.New("a project needs at least one open and one done state")
+	// ErrStateNotEmpty refuses deleting a state that still has todos without saying where they go
+	ErrStateNotEmpty = errors.New("the state still has todos")
+)
+
+type WorkflowStateRepository struct {
+	db *pgxpool.Pool
+	// systemDB may bypass row-level security; only withSystemScope uses it
+	systemDB *pgxpool.Pool
+}
+
+func NewWorkflowStateRepository(db, systemDB *pgxpool.Pool) *WorkflowStateRepository {
+	return &WorkflowStateRepository{db: db, systemDB: systemDB}
+}
+
+// States are read and changed per project; callers check the user's role on
+// the project first. Changes to a project's states are serialized with an
+// advisory lock so positions stay 1..n without gaps.
+const workflowStateColumns = `id, project_id, name, position, is_done, created_at, updated_at`
+
+func scanWorkflowState(row pgx.Row, state *models.WorkflowState) error {
+	return row.Scan(
+		&state.ID,
+		&state.ProjectID,
+		&state.Name,
+		&state.Position,
+		&state.IsDone,
+		&state.CreatedAt,
+		&state.UpdatedAt,
+	)
+}
+
+func lockWorkflow(ctx context.Context, tx pgx.Tx, projectID int) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('workflow_states'), $1)`, projectID)
+	return err
+}
+
+// ListByProject returns the project's states in board order.
+func (r *WorkflowStateRepository) ListByProject(ctx context.Context, projectID, userID int) ([]models.WorkflowState, error) {
+	query := `
+		SELECT ` + workflowStateColumns + `
+		FROM workflow_states
+		WHERE project_id = $1
+		ORDER BY position ASC, id ASC
+	`
+
+	states := []models.WorkflowState{}
+	err := withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, projectID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var state models.WorkflowState
+			if err := scanWorkflowState(rows, &state); err != nil {
+				return err
+			}
+			states = append(states, state)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (r *WorkflowStateRepository) GetByID(ctx context.Context, id, userID int) (*models.WorkflowState, error) {
+	query := `SELECT ` + workflowStateColumns + ` FROM workflow_states WHERE id = $1`
+
+	state := &models.WorkflowState{}
+	err := withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		return scanWorkflowState(tx.QueryRow(ctx, query, id), state)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("workflow state not found")
+		}
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Create adds a state at its position, shifting the states from there on one
+// place right. A position of 0 or past the end puts it last.
+func (r *WorkflowStateRepository) Create(ctx context.Context, state *models.WorkflowState, userID int) error {
+	return withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		if err := lockWorkflow(ctx, tx, state.ProjectID); err != nil {
+			return err
+		}
+
+		var count int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM workflow_states WHERE project_id = $1`, state.ProjectID).Scan(&count); err != nil {
+			return err
+		}
+		if state.Position < 1 || state.Position > count+1 {
+			state.Position = count + 1
+		}
+
+		_, err := tx.Exec(ctx, `
+			UPDATE workflow_states SET position = position + 1
+			WHERE project_id = $1 AND position >= $2
+		`, state.ProjectID, state.Position)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO workflow_states (project_id, name, position, is_done)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at, updated_at
+		`, state.ProjectID, state.Name, state.Position, state.IsDone).Scan(&state.ID, &state.CreatedAt, &state.UpdatedAt)
+		if isUniqueViolation(err) {
+			return ErrDuplicateStateName
+		}
+		return err
+	})
+}
+
+// otherStates counts the project's done and open states other than id.
+func otherStates(ctx context.Context, tx pgx.Tx, projectID, id int) (done, open int, err error) {
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE is_done), COUNT(*) FILTER (WHERE NOT is_done)
+		FROM workflow_states
+		WHERE project_id = $1 AND id <> $2
+	`, projectID, id).Scan(&done, &open)
+	return done, open, err
+}
+
+// Update renames the state, moves it to its position (clamped to the board)
+// and, if its done flag changed, completes or reopens its todos to match.
+// The todos may belong to anyone in the project, so this runs in the system
+// scope on behalf of userID.
+func (r *WorkflowStateRepository) Update(ctx context.Context, state *models.WorkflowState, userID int) error {
+	return withSystemScopeAs(ctx, r.systemDB, userID, func(tx pgx.Tx) error {
+		if err := lockWorkflow(ctx, tx, state.ProjectID); err != nil {
+			return err
+		}
+
+		var oldPosition, count int
+		var wasDone bool
+		err := tx.QueryRow(ctx, `
+			SELECT position, is_done, (SELECT COUNT(*) FROM workflow_states WHERE project_id = $2)
+			FROM workflow_states
+			WHERE id = $1 AND project_id = $2
+		`, state.ID, state.ProjectID).Scan(&oldPosition, &wasDone, &count)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.New("workflow state not found")
+			}
+			return err
+		}
+
+		if wasDone != state.IsDone {
+			done, open, err := otherStates(ctx, tx, state.ProjectID, state.ID)
+			if err != nil {
+				return err
+			}
+			if (wasDone && done == 0) || (!wasDone && open == 0) {
+				return ErrLastState
+			}
+		}
+
+		if state.Position < 1 {
+			state.Position = oldPosition
+		}
+		state.Position = min(state.Position, count)
+		if state.Position < oldPosition {
+			_, err = tx.Exec(ctx, `
+				UPDATE workflow_states SET position = position + 1
+				WHERE project_id = $1 AND position >= $2 AND position < $3
+			`, state.ProjectID, state.Position, oldPosition)
+		} else if state.Position > oldPosition {
+			_, err = tx.Exec(ctx, `
+				UPDATE workflow_states SET position = position - 1
+				WHERE project_id = $1 AND position > $2 AND position <= $3
+			`, state.ProjectID, oldPosition, state.Position)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, `
+			UPDATE workflow_states SET name = $1, position = $2, is_done = $3
+			WHERE id = $4
+			RETURNING created_at, updated_at
+		`, state.Name, state.Position, state.IsDone, state.ID).Scan(&state.CreatedAt, &state.UpdatedAt)
+		if isUniqueViolation(err) {
+			return ErrDuplicateStateName
+		}
+		if err != nil {
+			return err
+		}
+
+		if wasDone != state.IsDone {
+			_, err = tx.Exec(ctx, `
+				UPDATE todos SET completed = $2, updated_at = NOW()
+				WHERE state_id = $1 AND completed <> $2
+			`, state.ID, state.IsDone)
+		}
+		return err
+	})
+}
+
+// Delete removes the state. Its todos move to moveTo, another state of the
+// same project, taking on its done flag; without moveTo the state must be
+// empty. Like Update, this runs in the system scope on behalf of userID.
+func (r *WorkflowStateRepository) Delete(ctx context.Context, state *models.WorkflowState, moveTo *int, userID int) error {
+	return withSystemScopeAs(ctx, r.systemDB, userID, func(tx pgx.Tx) error {
+		if err := lockWorkflow(ctx, tx, state.ProjectID); err != nil {
+			return err
+		}
+
+		done, open, err := otherStates(ctx, tx, state.ProjectID, state.ID)
+		if err != nil {
+			return err
+		}
+		if (state.IsDone && done == 0) || (!state.IsDone && open == 0) {
+			return ErrLastState
+		}
+
+		if moveTo == nil {
+			var hasTodos bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM todos WHERE state_id = $1)`, state.ID).Scan(&hasTodos); err != nil {
+				return err
+			}
+			if hasTodos {
+				return ErrStateNotEmpty
+			}
+		} else {
+			result, err := tx.Exec(ctx, `
+				UPDATE todos t SET state_id = ws.id, completed = ws.is_done, updated_at = NOW()
+				FROM workflow_states ws
+				WHERE t.state_id = $1 AND ws.id = $2 AND ws.project_id = $3 AND ws.id <> $1
+			`, state.ID, *moveTo, state.ProjectID)
+			if err != nil {
+				return err
+			}
+			if result.RowsAffected() == 0 {
+				var valid bool
+				err := tx.QueryRow(ctx, `
+					SELECT EXISTS (SELECT 1 FROM workflow_states WHERE id = $1 AND project_id = $2 AND id <> $3)
+				`, *moveTo, state.ProjectID, state.ID).Scan(&valid)
+				if err != nil {
+					return err
+				}
+				if !valid {
+					return errors.New("target state not found")
+				}
+			}
+		}
+
+		result, err := tx.Exec(ctx, `DELETE FROM workflow_states WHERE id = $1 AND project_id = $2`, state.ID, state.ProjectID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return errors.New("workflow state not found")
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE workflow_states SET position = position - 1
+			WHERE project_id = $1 AND position > $2
+		`, state.ProjectID, state.Position)
+		return err
+	})
+}
+
+// ListTransitions returns the todo's state changes, oldest first. Callers
+// check the user can see the todo.
+func (r *WorkflowStateRepository) ListTransitions(ctx context.Context, todoID, userID int) ([]models.StateTransition, error) {
+	query := `
+		SELECT id, todo_id, from_state_id, to_state_id, from_state, to_state, user_id, created_at
+		FROM todo_state_transitions
+		WHERE todo_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	transitions := []models.StateTransition{}
+	err := withUserScope(ctx, r.db, userID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, todoID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var t models.StateTransition
+			err := rows.Scan(&t.ID, &t.TodoID, &t.FromStateID, &t.ToStateID, &t.FromState, &t.ToState, &t.UserID, &t.CreatedAt)
+			if err != nil {
+				return err
+			}
+			transitions = append(transitions, t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
@@ -0,0 +1,394 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pigeio/todo-api/internal/models"
+)
+
+// ErrPersonalWorkspace is returned when inviting to or handing over a user's
+// personal workspace, which only ever has that user as its member.
+var ErrPersonalWorkspace = errors.New("a personal workspace cannot be shared or transferred")
+
+type WorkspaceRepository struct {
+	db *pgxpool.Pool
+	// systemDB may bypass row-level security; only withSystemScope uses it
+	systemDB *pgxpool.Pool
+}
+
+func NewWorkspaceRepository(db, systemDB *pgxpool.Pool) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db, systemDB: systemDB}
+}
+
+// createPersonalWorkspace gives a freshly registered user their own workspace.
+// It runs inside the registration transaction.
+func createPersonalWorkspace(ctx context.Context, tx pgx.Tx, user *models.User) error {
+	var workspaceID int
+	err := tx.QueryRow(ctx, `
+		INSERT INTO workspaces (name, personal_user_id)
+		VALUES ($1, $2)
+		RETURNING id
+	`, user.Name+"'s workspace", user.ID).Scan(&workspaceID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, 'owner')
+	`, workspaceID, user.ID)
+	return err
+}
+
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace, ownerID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspaces (name)
+		VALUES ($1)
+		RETURNING id, created_at, updated_at
+	`, workspace.Name).Scan(&workspace.ID, &workspace.CreatedAt, &workspace.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, 'owner')
+	`, workspace.ID, ownerID)
+	if err != nil {
+		return err
+	}
+
+	workspace.Role = models.WorkspaceRoleOwner
+	return tx.Commit(ctx)
+}
+
+func (r *WorkspaceRepository) GetByUserID(ctx context.Context, userID int) ([]models.Workspace, error) {
+	query := `
+		SELECT w.id, w.name, w.personal_user_id IS NOT NULL, m.role, w.created_at, w.updated_at
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = $1
+		ORDER BY w.personal_user_id IS NULL, w.name ASC, w.id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []models.Workspace{}
+	for rows.Next() {
+		var ws models.Workspace
+		if err := rows.Scan(&ws.ID, &ws.Name, &ws.Personal, &ws.Role, &ws.CreatedAt, &ws.UpdatedAt); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, ws)
+	}
+
+	return workspaces, rows.Err()
+}
+
+// GetPersonalID returns the ID of the user's personal workspace, which is the
+// active workspace when a request does not name one.
+func (r *WorkspaceRepository) GetPersonalID(ctx context.Context, userID int) (int, error) {
+	var id int
+	err := r.db.QueryRow(ctx, `SELECT id FROM workspaces WHERE personal_user_id = $1`, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, errors.New("workspace not found")
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+const workspaceMemberColumns = `m.workspace_id, m.user_id, u.name, u.email, m.role, m.created_at`
+
+func scanWorkspaceMember(row pgx.Row, member *models.WorkspaceMember) error {
+	return row.Scan(
+		&member.WorkspaceID,
+		&member.UserID,
+		&member.Name,
+		&member.Email,
+		&member.Role,
+		&member.CreatedAt,
+	)
+}
+
+func (r *WorkspaceRepository) GetMember(ctx context.Context, workspaceID, userID int) (*models.WorkspaceMember, error) {
+	query := `
+		SELECT ` + workspaceMemberColumns + `
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1 AND m.user_id = $2
+	`
+
+	member := &models.WorkspaceMember{}
+	if err := scanWorkspaceMember(r.db.QueryRow(ctx, query, workspaceID, userID), member); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("member not found")
+		}
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID int) ([]models.WorkspaceMember, error) {
+	query := `
+		SELECT ` + workspaceMemberColumns + `
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY m.created_at ASC, m.user_id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.WorkspaceMember{}
+	for rows.Next() {
+		var member models.WorkspaceMember
+		if err := scanWorkspaceMember(rows, &member); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// RemoveMember takes a user out of a workspace. Their shares and assignments there
+// are dropped, and whatever they owned is handed to the workspace owner so the
+// workspace keeps its data. The owner cannot be removed.
+//
+// This has to touch todos and projects the acting admin may not be able to see,
+// so it runs in the system scope, bypassing row-level security.
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID int) error {
+	return withSystemScope(ctx, r.systemDB, func(tx pgx.Tx) error {
+		var ownerID int
+		err := tx.QueryRow(ctx, `
+			SELECT user_id FROM workspace_members
+			WHERE workspace_id = $1 AND role = 'owner'
+		`, workspaceID).Scan(&ownerID)
+		if err != nil {
+			return err
+		}
+		if ownerID == userID {
+			return errors.New("the workspace owner cannot be removed")
+		}
+
+		result, err := tx.Exec(ctx, `
+			DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2
+		`, workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return errors.New("member not found")
+		}
+
+		cleanup := []string{
+			`DELETE FROM shares s USING todos t WHERE s.todo_id = t.id AND t.workspace_id = $1 AND s.user_id = $2`,
+			`DELETE FROM shares s USING projects p WHERE s.project_id = p.id AND p.workspace_id = $1 AND s.user_id = $2`,
+			`UPDATE todos SET assignee_id = NULL WHERE workspace_id = $1 AND assignee_id = $2`,
+		}
+		for _, stmt := range cleanup {
+			if _, err := tx.Exec(ctx, stmt, workspaceID, userID); err != nil {
+				return err
+			}
+		}
+
+		handOver := []string{
+			`UPDATE todos SET user_id = $3 WHERE workspace_id = $1 AND user_id = $2`,
+			`UPDATE projects SET user_id = $3 WHERE workspace_id = $1 AND user_id = $2`,
+		}
+		for _, stmt := range handOver {
+			if _, err := tx.Exec(ctx, stmt, workspaceID, userID, ownerID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// TransferOwnership makes another member the owner; the previous owner stays on as an admin.
+func (r *WorkspaceRepository) TransferOwnership(ctx context.Context, workspaceID, fromUserID, toUserID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var personal bool
+	err = tx.QueryRow(ctx, `
+		SELECT personal_user_id IS NOT NULL FROM workspaces WHERE id = $1 FOR UPDATE
+	`, workspaceID).Scan(&personal)
+	if err != nil {
+		return err
+	}
+	if personal {
+		return ErrPersonalWorkspace
+	}
+
+	// Demote first: the partial unique index allows only one owner at a time
+	result, err := tx.Exec(ctx, `
+		UPDATE workspace_members SET role = 'admin'
+		WHERE workspace_id = $1 AND user_id = $2 AND role = 'owner'
+	`, workspaceID, fromUserID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("only the owner can transfer the workspace")
+	}
+
+	result, err = tx.Exec(ctx, `
+		UPDATE workspace_members SET role = 'owner'
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, toUserID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("member not found")
+	}
+
+	return tx.Commit(ctx)
+}
+
+const workspaceInvitationColumns = `
+	i.id, i.workspace_id, w.name, i.email, i.role, i.status, i.invited_by, i.created_at, i.responded_at
+`
+
+func scanWorkspaceInvitation(row pgx.Row, inv *models.WorkspaceInvitation) error {
+	return row.Scan(
+		&inv.ID,
+		&inv.WorkspaceID,
+		&inv.WorkspaceName,
+		&inv.Email,
+		&inv.Role,
+		&inv.Status,
+		&inv.InvitedBy,
+		&inv.CreatedAt,
+		&inv.RespondedAt,
+	)
+}
+
+// Invite creates a pending invitation, or re-opens an earlier one for the same email.
+// Personal workspaces cannot be invited to.
+func (r *WorkspaceRepository) Invite(ctx context.Context, inv *models.WorkspaceInvitation) error {
+	query := `
+		WITH upserted AS (
+			INSERT INTO workspace_invitations (workspace_id, email, role, invited_by)
+			SELECT id, $2, $3, $4 FROM workspaces
+			WHERE id = $1 AND personal_user_id IS NULL
+			ON CONFLICT (workspace_id, email) DO UPDATE SET
+				role = EXCLUDED.role,
+				invited_by = EXCLUDED.invited_by,
+				status = 'pending',
+				created_at = NOW(),
+				responded_at = NULL
+			RETURNING id, status, created_at
+		)
+		SELECT u.id, w.name, u.status, u.created_at
+		FROM upserted u
+		JOIN workspaces w ON w.id = $1
+	`
+
+	err := r.db.QueryRow(ctx, query, inv.WorkspaceID, inv.Email, inv.Role, inv.InvitedBy).
+		Scan(&inv.ID, &inv.WorkspaceName, &inv.Status, &inv.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrPersonalWorkspace
+	}
+	return err
+}
+
+func (r *WorkspaceRepository) ListPendingInvitations(ctx context.Context, email string) ([]models.WorkspaceInvitation, error) {
+	query := `
+		SELECT ` + workspaceInvitationColumns + `
+		FROM workspace_invitations i
+		JOIN workspaces w ON w.id = i.workspace_id
+		WHERE i.email = $1 AND i.status = 'pending'
+		ORDER BY i.created_at DESC, i.id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.WorkspaceInvitation{}
+	for rows.Next() {
+		var inv models.WorkspaceInvitation
+		if err := scanWorkspaceInvitation(rows, &inv); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, rows.Err()
+}
+
+// RespondToInvitation accepts or declines a pending invitation sent to email.
+// Accepting adds userID to the workspace with the invited role.
+func (r *WorkspaceRepository) RespondToInvitation(ctx context.Context, id, userID int, email string, accept bool) (*models.WorkspaceInvitation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	status := models.ShareStatusDeclined
+	if accept {
+		status = models.ShareStatusAccepted
+	}
+
+	query := `
+		WITH updated AS (
+			UPDATE workspace_invitations
+			SET status = $1, responded_at = NOW()
+			WHERE id = $2 AND email = $3 AND status = 'pending'
+			RETURNING *
+		)
+		SELECT ` + workspaceInvitationColumns + `
+		FROM updated i
+		JOIN workspaces w ON w.id = i.workspace_id
+	`
+
+	inv := &models.WorkspaceInvitation{}
+	if err := scanWorkspaceInvitation(tx.QueryRow(ctx, query, status, id, email), inv); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("invitation not found")
+		}
+		return nil, err
+	}
+
+	if accept {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO workspace_members (workspace_id, user_id, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+		`, inv.WorkspaceID, userID, inv.Role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return inv, tx.Commit(ctx)
+}
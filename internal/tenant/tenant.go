@@ -0,0 +1,43 @@
+// Package tenant carries the active workspace through a request's context.
+//
+// The workspace middleware stores it after checking membership, and the
+// repositories read it back for every tenant-scoped query. A missing
+// workspace is an error rather than "no filter", so a code path that
+// forgets to go through the middleware fails closed.
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+type contextKey string
+
+const workspaceContextKey contextKey = "workspace"
+
+// ErrNoWorkspace is returned when a tenant-scoped query runs without an active workspace.
+var ErrNoWorkspace = errors.New("no active workspace")
+
+// Workspace is the active workspace and the caller's role in it.
+type Workspace struct {
+	ID   int
+	Role string
+}
+
+func WithWorkspace(ctx context.Context, ws Workspace) context.Context {
+	return context.WithValue(ctx, workspaceContextKey, ws)
+}
+
+func FromContext(ctx context.Context) (Workspace, bool) {
+	ws, ok := ctx.Value(workspaceContextKey).(Workspace)
+	return ws, ok && ws.ID > 0
+}
+
+// WorkspaceID returns the active workspace ID, or ErrNoWorkspace.
+func WorkspaceID(ctx context.Context) (int, error) {
+	ws, ok := FromContext(ctx)
+	if !ok {
+		return 0, ErrNoWorkspace
+	}
+	return ws.ID, nil
+}
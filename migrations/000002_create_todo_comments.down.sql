@@ -0,0 +1,5 @@
+-- migrations/000002_create_todo_comments.down.sql
+
+DROP TRIGGER IF EXISTS update_todo_comments_updated_at ON todo_comments;
+DROP INDEX IF EXISTS idx_todo_comments_todo_id;
+DROP TABLE IF EXISTS todo_comments;
@@ -0,0 +1,21 @@
+-- migrations/000002_create_todo_comments.up.sql
+
+-- Create todo_comments table
+CREATE TABLE IF NOT EXISTS todo_comments (
+    id SERIAL PRIMARY KEY,
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Comments are always listed per todo in creation order
+CREATE INDEX IF NOT EXISTS idx_todo_comments_todo_id ON todo_comments(todo_id, created_at);
+
+CREATE TRIGGER update_todo_comments_updated_at
+    BEFORE UPDATE ON todo_comments
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
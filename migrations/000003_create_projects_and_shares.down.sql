@@ -0,0 +1,14 @@
+-- migrations/000003_create_projects_and_shares.down.sql
+
+DROP VIEW IF EXISTS todo_access;
+DROP VIEW IF EXISTS project_access;
+
+DROP TRIGGER IF EXISTS update_projects_updated_at ON projects;
+
+DROP INDEX IF EXISTS idx_shares_user_id;
+DROP INDEX IF EXISTS idx_todos_project_id;
+DROP INDEX IF EXISTS idx_projects_user_id;
+
+DROP TABLE IF EXISTS shares;
+ALTER TABLE todos DROP COLUMN IF EXISTS project_id;
+DROP TABLE IF EXISTS projects;
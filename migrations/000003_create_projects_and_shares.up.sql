@@ -0,0 +1,71 @@
+-- migrations/000003_create_projects_and_shares.up.sql
+
+-- Projects group todos into lists that can be shared as a whole
+CREATE TABLE IF NOT EXISTS projects (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL;
+
+-- A share grants a user a role on exactly one todo or one project.
+-- It only takes effect once the invited user has accepted it.
+CREATE TABLE IF NOT EXISTS shares (
+    id SERIAL PRIMARY KEY,
+    todo_id INTEGER REFERENCES todos(id) ON DELETE CASCADE,
+    project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    invited_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP,
+    CHECK ((todo_id IS NULL) <> (project_id IS NULL)),
+    UNIQUE (todo_id, user_id),
+    UNIQUE (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id);
+CREATE INDEX IF NOT EXISTS idx_todos_project_id ON todos(project_id);
+CREATE INDEX IF NOT EXISTS idx_shares_user_id ON shares(user_id, status);
+
+CREATE TRIGGER update_projects_updated_at
+    BEFORE UPDATE ON projects
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- project_access and todo_access are the single source of truth for authorization.
+-- role_rank: 1 = viewer, 2 = editor, 3 = owner. A user reaching a row through
+-- several paths (direct share, project share, ownership) gets the highest role.
+CREATE OR REPLACE VIEW project_access AS
+SELECT project_id, user_id, MAX(role_rank) AS role_rank
+FROM (
+    SELECT p.id AS project_id, p.user_id, 3 AS role_rank
+    FROM projects p
+    UNION ALL
+    SELECT s.project_id, s.user_id,
+        CASE s.role WHEN 'owner' THEN 3 WHEN 'editor' THEN 2 ELSE 1 END
+    FROM shares s
+    WHERE s.project_id IS NOT NULL AND s.status = 'accepted'
+) grants
+GROUP BY project_id, user_id;
+
+CREATE OR REPLACE VIEW todo_access AS
+SELECT todo_id, user_id, MAX(role_rank) AS role_rank
+FROM (
+    SELECT t.id AS todo_id, t.user_id, 3 AS role_rank
+    FROM todos t
+    UNION ALL
+    SELECT s.todo_id, s.user_id,
+        CASE s.role WHEN 'owner' THEN 3 WHEN 'editor' THEN 2 ELSE 1 END
+    FROM shares s
+    WHERE s.todo_id IS NOT NULL AND s.status = 'accepted'
+    UNION ALL
+    SELECT t.id, pa.user_id, pa.role_rank
+    FROM todos t
+    JOIN project_access pa ON pa.project_id = t.project_id
+) grants
+GROUP BY todo_id, user_id;
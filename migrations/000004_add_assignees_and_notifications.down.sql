@@ -0,0 +1,7 @@
+-- migrations/000004_add_assignees_and_notifications.down.sql
+
+DROP INDEX IF EXISTS idx_notifications_user_id;
+DROP TABLE IF EXISTS notifications;
+
+DROP INDEX IF EXISTS idx_todos_assignee_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS assignee_id;
@@ -0,0 +1,4 @@
+-- migrations/000005_create_share_links.down.sql
+
+DROP INDEX IF EXISTS idx_share_links_user_id;
+DROP TABLE IF EXISTS share_links;
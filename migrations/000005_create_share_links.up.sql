@@ -0,0 +1,20 @@
+-- migrations/000005_create_share_links.up.sql
+
+-- Public, read-only links to a todo or project. Only a SHA-256 hash of the
+-- token is stored; the token itself is shown once, when the link is created.
+CREATE TABLE IF NOT EXISTS share_links (
+    id SERIAL PRIMARY KEY,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    todo_id INTEGER REFERENCES todos(id) ON DELETE CASCADE,
+    project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255),
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    access_count INTEGER NOT NULL DEFAULT 0,
+    last_accessed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((todo_id IS NULL) <> (project_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_user_id ON share_links(user_id);
@@ -0,0 +1,16 @@
+-- migrations/000006_create_workspaces.down.sql
+
+DROP INDEX IF EXISTS idx_todos_workspace_id;
+DROP INDEX IF EXISTS idx_projects_workspace_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE projects DROP COLUMN IF EXISTS workspace_id;
+
+DROP TRIGGER IF EXISTS update_workspaces_updated_at ON workspaces;
+
+DROP INDEX IF EXISTS idx_workspace_invitations_email;
+DROP INDEX IF EXISTS idx_workspace_members_user_id;
+DROP INDEX IF EXISTS idx_workspace_members_owner;
+
+DROP TABLE IF EXISTS workspace_invitations;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
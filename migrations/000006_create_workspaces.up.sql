@@ -0,0 +1,71 @@
+-- migrations/000006_create_workspaces.up.sql
+
+-- Workspaces are the tenant boundary: every todo and project belongs to exactly one.
+-- Each user gets a personal workspace (personal_user_id set) when they register.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    personal_user_id INTEGER UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('member', 'admin', 'owner')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+-- Exactly one owner per workspace
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_members_owner ON workspace_members(workspace_id) WHERE role = 'owner';
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('member', 'admin')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    invited_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP,
+    UNIQUE (workspace_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_invitations_email ON workspace_invitations(email, status);
+
+CREATE TRIGGER update_workspaces_updated_at
+    BEFORE UPDATE ON workspaces
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Give every existing user a personal workspace
+INSERT INTO workspaces (name, personal_user_id)
+SELECT name || '''s workspace', id FROM users;
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, personal_user_id, 'owner' FROM workspaces WHERE personal_user_id IS NOT NULL;
+
+-- Move existing todos and projects into their owner's personal workspace
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+UPDATE projects p SET workspace_id = w.id FROM workspaces w WHERE w.personal_user_id = p.user_id;
+ALTER TABLE projects ALTER COLUMN workspace_id SET NOT NULL;
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+UPDATE todos t SET workspace_id = w.id FROM workspaces w WHERE w.personal_user_id = t.user_id;
+ALTER TABLE todos ALTER COLUMN workspace_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_projects_workspace_id ON projects(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_todos_workspace_id ON todos(workspace_id);
+
+-- Sharing now only works inside a workspace. Keep existing shares working by
+-- making their recipients members of the workspace that holds the shared item.
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT DISTINCT COALESCE(t.workspace_id, p.workspace_id), s.user_id, 'member'
+FROM shares s
+LEFT JOIN todos t ON t.id = s.todo_id
+LEFT JOIN projects p ON p.id = s.project_id
+WHERE s.status = 'accepted'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+-- migrations/000007_enable_row_level_security.down.sql
+
+DROP POLICY IF EXISTS todo_comments_user_isolation ON todo_comments;
+ALTER TABLE todo_comments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE todo_comments DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS todos_user_isolation ON todos;
+ALTER TABLE todos NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE todos DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS projects_user_isolation ON projects;
+ALTER TABLE projects NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE projects DISABLE ROW LEVEL SECURITY;
+
+DROP FUNCTION IF EXISTS app_rls_bypassed();
+DROP FUNCTION IF EXISTS app_user_id();
@@ -0,0 +1,67 @@
+-- migrations/000007_enable_row_level_security.up.sql
+
+-- Row-level security as a second line of defense behind the repositories.
+-- Each transaction names its user through the app.user_id setting; rows of
+-- other users are invisible even to a query that forgets its own filter.
+--
+-- NOTE: superusers and roles with BYPASSRLS skip these policies entirely,
+-- so the application must connect as an ordinary role.
+
+CREATE OR REPLACE FUNCTION app_user_id()
+RETURNS INTEGER AS $$
+    SELECT NULLIF(current_setting('app.user_id', true), '')::INTEGER
+$$ LANGUAGE sql STABLE;
+
+-- Set only by background jobs and administrative operations
+CREATE OR REPLACE FUNCTION app_rls_bypassed()
+RETURNS BOOLEAN AS $$
+    SELECT COALESCE(current_setting('app.bypass_rls', true), '') = 'on'
+$$ LANGUAGE sql STABLE;
+
+-- Projects: visible to workspace members who own the project or accepted a share of it
+ALTER TABLE projects ENABLE ROW LEVEL SECURITY;
+ALTER TABLE projects FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY projects_user_isolation ON projects
+    USING (
+        app_rls_bypassed() OR (
+            workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = app_user_id())
+            AND (
+                user_id = app_user_id()
+                OR EXISTS (
+                    SELECT 1 FROM shares s
+                    WHERE s.project_id = projects.id AND s.user_id = app_user_id() AND s.status = 'accepted'
+                )
+            )
+        )
+    );
+
+-- Todos: visible to workspace members who own the todo, accepted a share of it,
+-- or can see its project (the subquery is itself filtered by the projects policy)
+ALTER TABLE todos ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todos FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY todos_user_isolation ON todos
+    USING (
+        app_rls_bypassed() OR (
+            workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = app_user_id())
+            AND (
+                user_id = app_user_id()
+                OR EXISTS (
+                    SELECT 1 FROM shares s
+                    WHERE s.todo_id = todos.id AND s.user_id = app_user_id() AND s.status = 'accepted'
+                )
+                OR EXISTS (SELECT 1 FROM projects p WHERE p.id = todos.project_id)
+            )
+        )
+    );
+
+-- Comments: visible whenever their todo is (again through the todos policy)
+ALTER TABLE todo_comments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_comments FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY todo_comments_user_isolation ON todo_comments
+    USING (
+        app_rls_bypassed()
+        OR EXISTS (SELECT 1 FROM todos t WHERE t.id = todo_comments.todo_id)
+    );
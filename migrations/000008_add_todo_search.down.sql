@@ -0,0 +1,15 @@
+-- migrations/000008_add_todo_search.down.sql
+
+DROP TRIGGER IF EXISTS update_todos_updated_at ON todos;
+CREATE TRIGGER update_todos_updated_at
+    BEFORE UPDATE ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS set_todos_search_language ON todos;
+DROP FUNCTION IF EXISTS set_todo_search_language();
+
+DROP INDEX IF EXISTS idx_todos_search_vector;
+ALTER TABLE todos DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE todos DROP COLUMN IF EXISTS search_language;
+ALTER TABLE users DROP COLUMN IF EXISTS search_language;
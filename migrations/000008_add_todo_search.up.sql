@@ -0,0 +1,40 @@
+-- migrations/000008_add_todo_search.up.sql
+
+-- Language used to stem the user's todos and their search queries
+ALTER TABLE users ADD COLUMN search_language REGCONFIG NOT NULL DEFAULT 'english';
+
+-- Each todo remembers the language it was indexed with; it follows its owner's
+-- setting (see set_todo_search_language below and SettingsRepository).
+ALTER TABLE todos ADD COLUMN search_language REGCONFIG NOT NULL DEFAULT 'english';
+
+UPDATE todos t SET search_language = u.search_language
+FROM users u WHERE u.id = t.user_id;
+
+-- Title matches rank above description matches
+ALTER TABLE todos ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector(search_language, COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector(search_language, COALESCE(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_todos_search_vector ON todos USING GIN (search_vector);
+
+CREATE OR REPLACE FUNCTION set_todo_search_language()
+RETURNS TRIGGER AS $$
+BEGIN
+    SELECT search_language INTO NEW.search_language FROM users WHERE id = NEW.user_id;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER set_todos_search_language
+    BEFORE INSERT ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION set_todo_search_language();
+
+-- Re-indexing a todo in another language is not an edit
+DROP TRIGGER IF EXISTS update_todos_updated_at ON todos;
+CREATE TRIGGER update_todos_updated_at
+    BEFORE UPDATE ON todos
+    FOR EACH ROW
+    WHEN (OLD.search_language IS NOT DISTINCT FROM NEW.search_language)
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,6 @@
+-- migrations/000009_add_trigram_search.down.sql
+
+DROP INDEX IF EXISTS idx_todos_description_trgm;
+DROP INDEX IF EXISTS idx_todos_title_trgm;
+
+-- The extension is left installed; other objects in the database may use it
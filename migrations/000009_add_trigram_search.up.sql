@@ -0,0 +1,7 @@
+-- migrations/000009_add_trigram_search.up.sql
+
+-- Trigram similarity for typo-tolerant search, similar todos and duplicate detection
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_todos_title_trgm ON todos USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_todos_description_trgm ON todos USING GIN (description gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- migrations/000010_create_saved_views.down.sql
+
+DROP TRIGGER IF EXISTS update_saved_views_updated_at ON saved_views;
+DROP INDEX IF EXISTS idx_saved_views_user_id;
+DROP TABLE IF EXISTS saved_views;
@@ -0,0 +1,22 @@
+-- migrations/000010_create_saved_views.up.sql
+
+-- Named todo filters ("smart lists"), private to their user within a workspace.
+-- filter holds the expression as typed; it is parsed again whenever the view runs.
+CREATE TABLE IF NOT EXISTS saved_views (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    filter TEXT NOT NULL DEFAULT '',
+    sort VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (workspace_id, user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_views_user_id ON saved_views(user_id, workspace_id);
+
+CREATE TRIGGER update_saved_views_updated_at
+    BEFORE UPDATE ON saved_views
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
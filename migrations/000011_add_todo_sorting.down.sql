@@ -0,0 +1,7 @@
+-- migrations/000011_add_todo_sorting.down.sql
+
+UPDATE saved_views SET sort = ltrim(sort, '-') WHERE sort IN ('-created_at', '-updated_at');
+UPDATE saved_views SET sort = '' WHERE sort NOT IN ('', 'created_at', 'updated_at', 'title');
+
+DROP INDEX IF EXISTS idx_todos_title_collated;
+DROP COLLATION IF EXISTS todo_title;
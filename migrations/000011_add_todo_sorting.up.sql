@@ -0,0 +1,19 @@
+-- migrations/000011_add_todo_sorting.up.sql
+
+-- Collation for sorting todo titles: language-aware, with embedded numbers
+-- compared by value ("Step 2" before "Step 10"). Servers built without ICU
+-- fall back to a copy of the database's own locale.
+DO $$
+BEGIN
+    CREATE COLLATION IF NOT EXISTS todo_title (provider = icu, locale = 'und-u-kn-true');
+EXCEPTION WHEN OTHERS THEN
+    EXECUTE format('CREATE COLLATION IF NOT EXISTS todo_title (provider = libc, locale = %L)',
+        (SELECT datcollate FROM pg_database WHERE datname = current_database()));
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_todos_title_collated ON todos ((title COLLATE todo_title));
+
+-- Saved views now use the sort= syntax, where a bare field sorts ascending;
+-- the old sort_by values for dates meant newest first
+UPDATE saved_views SET sort = '-' || sort WHERE sort IN ('created_at', 'updated_at');
@@ -0,0 +1,11 @@
+-- migrations/000012_add_todo_positions.down.sql
+
+DROP TRIGGER IF EXISTS update_todos_updated_at ON todos;
+CREATE TRIGGER update_todos_updated_at
+    BEFORE UPDATE ON todos
+    FOR EACH ROW
+    WHEN (OLD.search_language IS NOT DISTINCT FROM NEW.search_language)
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP INDEX IF EXISTS idx_todos_position;
+ALTER TABLE todos DROP COLUMN IF EXISTS position;
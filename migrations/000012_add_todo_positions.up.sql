@@ -0,0 +1,17 @@
+-- migrations/000012_add_todo_positions.up.sql
+
+-- Manual order: fractional index keys compared byte by byte (see
+-- internal/repository/position.go). Existing todos start without a key and
+-- sort after all others until the rebalancing job gives them one.
+ALTER TABLE todos ADD COLUMN position TEXT COLLATE "C";
+
+CREATE INDEX IF NOT EXISTS idx_todos_position ON todos(workspace_id, position, id);
+
+-- Moves set updated_at themselves; rebalancing keys is not an edit
+DROP TRIGGER IF EXISTS update_todos_updated_at ON todos;
+CREATE TRIGGER update_todos_updated_at
+    BEFORE UPDATE ON todos
+    FOR EACH ROW
+    WHEN (OLD.search_language IS NOT DISTINCT FROM NEW.search_language
+          AND OLD.position IS NOT DISTINCT FROM NEW.position)
+    EXECUTE FUNCTION update_updated_at_column();
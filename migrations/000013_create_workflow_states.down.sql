@@ -0,0 +1,7 @@
+-- migrations/000013_create_workflow_states.down.sql
+
+DROP TRIGGER IF EXISTS record_todos_state_transition ON todos;
+DROP FUNCTION IF EXISTS record_todo_state_transition();
+DROP TABLE IF EXISTS todo_state_transitions;
+ALTER TABLE todos DROP COLUMN IF EXISTS state_id;
+DROP TABLE IF EXISTS workflow_states;
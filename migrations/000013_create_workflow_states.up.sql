@@ -0,0 +1,96 @@
+-- migrations/000013_create_workflow_states.up.sql
+
+-- Per-project workflow: the columns of the project's board, in order. States
+-- marked is_done count as completed, so todos.completed keeps meaning "done"
+-- for clients that know nothing about states.
+CREATE TABLE IF NOT EXISTS workflow_states (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    position INTEGER NOT NULL,
+    is_done BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workflow_states_name ON workflow_states(project_id, lower(name));
+CREATE INDEX IF NOT EXISTS idx_workflow_states_position ON workflow_states(project_id, position);
+
+CREATE TRIGGER update_workflow_states_updated_at
+    BEFORE UPDATE ON workflow_states
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Todos outside a project have no state
+ALTER TABLE todos ADD COLUMN state_id INTEGER REFERENCES workflow_states(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_todos_state_id ON todos(state_id);
+
+-- Every change of state, with the names as they were at the time
+CREATE TABLE IF NOT EXISTS todo_state_transitions (
+    id SERIAL PRIMARY KEY,
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    from_state_id INTEGER REFERENCES workflow_states(id) ON DELETE SET NULL,
+    to_state_id INTEGER REFERENCES workflow_states(id) ON DELETE SET NULL,
+    from_state VARCHAR(50),
+    to_state VARCHAR(50),
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_state_transitions_todo_id ON todo_state_transitions(todo_id, created_at);
+
+-- The actor is the user the transaction runs for (see migrations/000007)
+CREATE OR REPLACE FUNCTION record_todo_state_transition()
+RETURNS TRIGGER AS $$
+DECLARE
+    from_id INTEGER;
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        IF OLD.state_id IS NOT DISTINCT FROM NEW.state_id THEN
+            RETURN NEW;
+        END IF;
+        from_id := OLD.state_id;
+    ELSIF NEW.state_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO todo_state_transitions (todo_id, from_state_id, to_state_id, from_state, to_state, user_id)
+    VALUES (
+        NEW.id, from_id, NEW.state_id,
+        (SELECT name FROM workflow_states WHERE id = from_id),
+        (SELECT name FROM workflow_states WHERE id = NEW.state_id),
+        app_user_id()
+    );
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER record_todos_state_transition
+    AFTER INSERT OR UPDATE OF state_id ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION record_todo_state_transition();
+
+-- Existing projects get the default workflow (models.DefaultWorkflowStates)
+-- and their todos the first state matching their completion. The backfill
+-- covers every user's todos, so it runs with RLS bypassed, and isn't an edit.
+SELECT set_config('app.bypass_rls', 'on', false);
+ALTER TABLE todos DISABLE TRIGGER update_todos_updated_at;
+
+INSERT INTO workflow_states (project_id, name, position, is_done)
+SELECT p.id, s.name, s.position, s.is_done
+FROM projects p
+CROSS JOIN (VALUES
+    ('Backlog', 1, false),
+    ('In Progress', 2, false),
+    ('Review', 3, false),
+    ('Done', 4, true)
+) AS s(name, position, is_done);
+
+UPDATE todos t SET state_id = ws.id
+FROM workflow_states ws
+WHERE ws.project_id = t.project_id
+  AND ws.name = CASE WHEN t.completed THEN 'Done' ELSE 'Backlog' END;
+
+ALTER TABLE todos ENABLE TRIGGER update_todos_updated_at;
+SELECT set_config('app.bypass_rls', '', false);
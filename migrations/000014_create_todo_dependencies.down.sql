@@ -0,0 +1,4 @@
+-- migrations/000014_create_todo_dependencies.down.sql
+
+ALTER TABLE users DROP COLUMN IF EXISTS blocked_completion;
+DROP TABLE IF EXISTS todo_dependencies;
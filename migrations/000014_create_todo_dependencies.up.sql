@@ -0,0 +1,20 @@
+-- migrations/000014_create_todo_dependencies.up.sql
+
+-- "todo_id is blocked by blocker_id". Cycles are refused when a dependency is
+-- added (see TodoRepository.AddDependency); the table itself only rules out
+-- a todo blocking itself.
+CREATE TABLE IF NOT EXISTS todo_dependencies (
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    blocker_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (todo_id, blocker_id),
+    CHECK (todo_id <> blocker_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_dependencies_blocker_id ON todo_dependencies(blocker_id);
+
+-- What happens when the user completes a todo that is still blocked:
+-- 'warn' completes it and says so, 'reject' refuses
+ALTER TABLE users ADD COLUMN blocked_completion VARCHAR(10) NOT NULL DEFAULT 'warn'
+    CHECK (blocked_completion IN ('warn', 'reject'));
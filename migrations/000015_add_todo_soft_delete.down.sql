@@ -0,0 +1,5 @@
+-- migrations/000015_add_todo_soft_delete.down.sql
+
+DELETE FROM todos WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_todos_deleted_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS deleted_at;
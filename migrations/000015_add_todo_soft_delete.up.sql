@@ -0,0 +1,7 @@
+-- migrations/000015_add_todo_soft_delete.up.sql
+
+-- Deleted todos go to the trash first; they are purged for good after the
+-- retention period (TRASH_RETENTION_DAYS) or when the user empties the trash.
+ALTER TABLE todos ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_todos_deleted_at ON todos(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,5 @@
+-- migrations/000016_add_todo_archive.down.sql
+
+ALTER TABLE users DROP COLUMN IF EXISTS auto_archive_days;
+DROP INDEX IF EXISTS idx_todos_archived_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS archived_at;
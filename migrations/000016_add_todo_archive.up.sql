@@ -0,0 +1,10 @@
+-- migrations/000016_add_todo_archive.up.sql
+
+-- Archived todos are kept and can still be opened, but leave the default
+-- listings and counts (GET /todos?status=archived lists them).
+ALTER TABLE todos ADD COLUMN archived_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_todos_archived_at ON todos(workspace_id, archived_at) WHERE archived_at IS NOT NULL;
+
+-- Completed todos untouched for this many days are archived automatically; NULL turns it off
+ALTER TABLE users ADD COLUMN auto_archive_days INTEGER CHECK (auto_archive_days > 0);
@@ -0,0 +1,6 @@
+-- migrations/000017_create_todo_revisions.down.sql
+
+DROP TRIGGER IF EXISTS record_todos_revision ON todos;
+DROP FUNCTION IF EXISTS record_todo_revision();
+DROP FUNCTION IF EXISTS todo_revision_snapshot(JSONB);
+DROP TABLE IF EXISTS todo_revisions;
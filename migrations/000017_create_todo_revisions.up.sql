@@ -0,0 +1,92 @@
+-- migrations/000017_create_todo_revisions.up.sql
+
+-- Append-only history of todos. Every insert and every change to a tracked
+-- field adds a revision with the changed fields (old and new values) and a
+-- snapshot of all tracked fields afterwards, which is what reverting restores.
+-- The actor is the user the transaction runs for (see migrations/000007);
+-- background jobs leave it NULL. Moving a todo in the manual order is not tracked.
+CREATE TABLE IF NOT EXISTS todo_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (todo_id, revision)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_revisions_workspace ON todo_revisions(workspace_id, created_at DESC, id DESC);
+
+-- Revisions are visible whenever their todo is
+ALTER TABLE todo_revisions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_revisions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY todo_revisions_user_isolation ON todo_revisions
+    USING (
+        app_rls_bypassed()
+        OR EXISTS (SELECT 1 FROM todos t WHERE t.id = todo_revisions.todo_id)
+    );
+
+CREATE OR REPLACE FUNCTION todo_revision_snapshot(todo JSONB)
+RETURNS JSONB AS $$
+    SELECT jsonb_object_agg(field, todo -> field)
+    FROM unnest(ARRAY['title', 'description', 'completed', 'project_id', 'assignee_id',
+                      'state_id', 'archived_at', 'deleted_at']) AS field
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION record_todo_revision()
+RETURNS TRIGGER AS $$
+DECLARE
+    old_row JSONB := '{}';
+    new_row JSONB := todo_revision_snapshot(to_jsonb(NEW));
+    changed JSONB := '[]';
+    kind TEXT := 'create';
+    field_name TEXT;
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        old_row := todo_revision_snapshot(to_jsonb(OLD));
+        kind := CASE
+            WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+            WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+            ELSE 'update'
+        END;
+    END IF;
+
+    FOR field_name IN SELECT jsonb_object_keys(new_row) LOOP
+        IF TG_OP = 'INSERT' OR old_row -> field_name IS DISTINCT FROM new_row -> field_name THEN
+            changed := changed || jsonb_build_object(
+                'field', field_name, 'old', old_row -> field_name, 'new', new_row -> field_name);
+        END IF;
+    END LOOP;
+
+    IF jsonb_array_length(changed) = 0 THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO todo_revisions (todo_id, workspace_id, revision, action, user_id, changes, snapshot)
+    VALUES (
+        NEW.id, NEW.workspace_id,
+        COALESCE((SELECT MAX(revision) FROM todo_revisions WHERE todo_id = NEW.id), 0) + 1,
+        kind, app_user_id(), changed, new_row
+    );
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER record_todos_revision
+    AFTER INSERT OR UPDATE ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION record_todo_revision();
+
+-- History starts now: each existing todo gets a 'snapshot' revision of how it
+-- looks today, so it can be reverted to that after its first tracked change.
+SELECT set_config('app.bypass_rls', 'on', false);
+
+INSERT INTO todo_revisions (todo_id, workspace_id, revision, action, user_id, changes, snapshot, created_at)
+SELECT t.id, t.workspace_id, 1, 'snapshot', NULL, '[]', todo_revision_snapshot(to_jsonb(t)), t.updated_at
+FROM todos t;
+
+SELECT set_config('app.bypass_rls', '', false);
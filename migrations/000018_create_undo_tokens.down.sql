@@ -0,0 +1,3 @@
+-- migrations/000018_create_undo_tokens.down.sql
+
+DROP TABLE IF EXISTS undo_tokens;
@@ -0,0 +1,18 @@
+-- migrations/000018_create_undo_tokens.up.sql
+
+-- Single-use tokens that put todos back the way they were before a change.
+-- todos holds the prior state of every todo the change touched. Like share
+-- links, only the token's hash is stored.
+CREATE TABLE IF NOT EXISTS undo_tokens (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    todos JSONB NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    redeemed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_undo_tokens_expires_at ON undo_tokens(expires_at);
@@ -0,0 +1,5 @@
+-- migrations/000019_add_todo_version.down.sql
+
+DROP TRIGGER IF EXISTS bump_todos_version ON todos;
+DROP FUNCTION IF EXISTS bump_todo_version();
+ALTER TABLE todos DROP COLUMN IF EXISTS version;
@@ -0,0 +1,19 @@
+-- migrations/000019_add_todo_version.up.sql
+
+-- Optimistic concurrency: every change to a todo bumps its version, which is
+-- what its ETag names. The API refuses writes based on an older version
+-- (If-Match, and the version check in TodoRepository.Update).
+ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_todo_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER bump_todos_version
+    BEFORE UPDATE ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_todo_version();
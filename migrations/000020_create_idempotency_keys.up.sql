@@ -0,0 +1,23 @@
+-- migrations/000020_create_idempotency_keys.up.sql
+
+-- Responses to requests sent with an Idempotency-Key header, so a retry with
+-- the same key gets the first response again instead of repeating the change.
+-- Keys are per user. The fingerprint (a hash of the method, path and body)
+-- catches a key reused for a different request. status is NULL while the
+-- first request is still running; locked_at tells when it started, so a
+-- request that never finished (the server died) doesn't hold the key forever.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status INTEGER,
+    headers JSONB,
+    body BYTEA,
+    locked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,51 @@
+-- migrations/000021_add_todo_sync.down.sql
+
+DROP TRIGGER IF EXISTS record_todos_tombstone ON todos;
+DROP FUNCTION IF EXISTS record_todo_tombstone();
+DROP TABLE IF EXISTS todo_tombstones;
+
+DROP TRIGGER IF EXISTS stamp_todos_change ON todos;
+DROP FUNCTION IF EXISTS stamp_todo_change();
+DROP INDEX IF EXISTS idx_todos_changed_txid;
+ALTER TABLE todos DROP COLUMN IF EXISTS changed_txid;
+
+CREATE OR REPLACE FUNCTION record_todo_revision()
+RETURNS TRIGGER AS $$
+DECLARE
+    old_row JSONB := '{}';
+    new_row JSONB := todo_revision_snapshot(to_jsonb(NEW));
+    changed JSONB := '[]';
+    kind TEXT := 'create';
+    field_name TEXT;
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        old_row := todo_revision_snapshot(to_jsonb(OLD));
+        kind := CASE
+            WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+            WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+            ELSE 'update'
+        END;
+    END IF;
+
+    FOR field_name IN SELECT jsonb_object_keys(new_row) LOOP
+        IF TG_OP = 'INSERT' OR old_row -> field_name IS DISTINCT FROM new_row -> field_name THEN
+            changed := changed || jsonb_build_object(
+                'field', field_name, 'old', old_row -> field_name, 'new', new_row -> field_name);
+        END IF;
+    END LOOP;
+
+    IF jsonb_array_length(changed) = 0 THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO todo_revisions (todo_id, workspace_id, revision, action, user_id, changes, snapshot)
+    VALUES (
+        NEW.id, NEW.workspace_id,
+        COALESCE((SELECT MAX(revision) FROM todo_revisions WHERE todo_id = NEW.id), 0) + 1,
+        kind, app_user_id(), changed, new_row
+    );
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+ALTER TABLE todo_revisions DROP COLUMN IF EXISTS version;
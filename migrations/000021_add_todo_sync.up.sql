@@ -0,0 +1,113 @@
+-- migrations/000021_add_todo_sync.up.sql
+
+-- Delta sync (POST /sync). changed_txid is the transaction that last inserted
+-- or changed a todo. A sync token holds the oldest transaction that was still
+-- running when the client synced (pg_snapshot_xmin), so every todo whose
+-- changed_txid is at least that is one the client may not have seen yet.
+-- Transaction IDs aren't in commit order, which is why the token can't simply
+-- be the highest one seen; the price is that some changes are sent twice.
+ALTER TABLE todos ADD COLUMN changed_txid XID8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS idx_todos_changed_txid ON todos(workspace_id, changed_txid);
+
+CREATE OR REPLACE FUNCTION stamp_todo_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.changed_txid = pg_current_xact_id();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stamp_todos_change
+    BEFORE UPDATE ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION stamp_todo_change();
+
+-- Todos that were deleted for good (purged from the trash), so clients that
+-- last synced before can drop them. They are kept for a while and then purged
+-- themselves; older sync tokens are refused. There is no foreign key to
+-- workspaces, so deleting a workspace (and with it its todos) isn't held up,
+-- and no row-level security: a tombstone only tells that an ID is gone.
+CREATE TABLE IF NOT EXISTS todo_tombstones (
+    todo_id INTEGER PRIMARY KEY,
+    workspace_id INTEGER NOT NULL,
+    deleted_txid XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_tombstones_workspace ON todo_tombstones(workspace_id, deleted_txid);
+CREATE INDEX IF NOT EXISTS idx_todo_tombstones_deleted_at ON todo_tombstones(deleted_at);
+
+CREATE OR REPLACE FUNCTION record_todo_tombstone()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO todo_tombstones (todo_id, workspace_id)
+    VALUES (OLD.id, OLD.workspace_id)
+    ON CONFLICT (todo_id) DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER record_todos_tombstone
+    AFTER DELETE ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION record_todo_tombstone();
+
+-- Revisions remember the version they made, so a sync can tell which fields
+-- changed on the server since the version a client's change was based on.
+ALTER TABLE todo_revisions ADD COLUMN version INTEGER;
+
+CREATE OR REPLACE FUNCTION record_todo_revision()
+RETURNS TRIGGER AS $$
+DECLARE
+    old_row JSONB := '{}';
+    new_row JSONB := todo_revision_snapshot(to_jsonb(NEW));
+    changed JSONB := '[]';
+    kind TEXT := 'create';
+    field_name TEXT;
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        old_row := todo_revision_snapshot(to_jsonb(OLD));
+        kind := CASE
+            WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+            WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+            ELSE 'update'
+        END;
+    END IF;
+
+    FOR field_name IN SELECT jsonb_object_keys(new_row) LOOP
+        IF TG_OP = 'INSERT' OR old_row -> field_name IS DISTINCT FROM new_row -> field_name THEN
+            changed := changed || jsonb_build_object(
+                'field', field_name, 'old', old_row -> field_name, 'new', new_row -> field_name);
+        END IF;
+    END LOOP;
+
+    IF jsonb_array_length(changed) = 0 THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO todo_revisions (todo_id, workspace_id, revision, action, user_id, changes, snapshot, version)
+    VALUES (
+        NEW.id, NEW.workspace_id,
+        COALESCE((SELECT MAX(revision) FROM todo_revisions WHERE todo_id = NEW.id), 0) + 1,
+        kind, app_user_id(), changed, new_row, NEW.version
+    );
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Earlier revisions get an upper bound of their version: each later revision
+-- bumped it at least once, but moves in the manual order bump it without one.
+-- Erring high can only make a sync see a conflict that wasn't, never miss one.
+SELECT set_config('app.bypass_rls', 'on', false);
+
+UPDATE todo_revisions r
+SET version = GREATEST(t.version - (latest.revision - r.revision), 1)
+FROM todos t, (
+    SELECT todo_id, MAX(revision) AS revision FROM todo_revisions GROUP BY todo_id
+) latest
+WHERE t.id = r.todo_id AND latest.todo_id = r.todo_id;
+
+SELECT set_config('app.bypass_rls', '', false);
+
+ALTER TABLE todo_revisions ALTER COLUMN version SET NOT NULL;
@@ -0,0 +1,8 @@
+-- migrations/000022_require_role_to_bypass_rls.down.sql
+
+-- The todo_api_system role is left in place: it is shared by every database
+-- of the cluster and may still hold grants.
+CREATE OR REPLACE FUNCTION app_rls_bypassed()
+RETURNS BOOLEAN AS $$
+    SELECT COALESCE(current_setting('app.bypass_rls', true), '') = 'on'
+$$ LANGUAGE sql STABLE;
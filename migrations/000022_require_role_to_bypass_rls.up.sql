@@ -0,0 +1,25 @@
+-- migrations/000022_require_role_to_bypass_rls.up.sql
+
+-- Bypassing row-level security used to take a setting, app.bypass_rls, that
+-- any statement on the application's connection could set for itself. It now
+-- takes membership of todo_api_system: background jobs and administrative
+-- operations connect as a separate role that is a member (SYSTEM_DATABASE_URL),
+-- and the role requests use (DATABASE_URL) must not be one, so nothing it runs
+-- can switch the policies off. Grant the role with
+--
+--     GRANT todo_api_system TO <system login role>;
+--
+-- Migrations run as the tables' owner or a superuser; superusers, like roles
+-- with BYPASSRLS, skip the policies anyway. Earlier migrations that set
+-- app.bypass_rls for a backfill ran before this one and are unaffected.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'todo_api_system') THEN
+        CREATE ROLE todo_api_system NOLOGIN;
+    END IF;
+END $$;
+
+CREATE OR REPLACE FUNCTION app_rls_bypassed()
+RETURNS BOOLEAN AS $$
+    SELECT pg_has_role(current_user, 'todo_api_system', 'MEMBER')
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,36 @@
+-- migrations/000023_enable_row_level_security_on_later_tables.down.sql
+
+DROP POLICY IF EXISTS todo_tombstones_user_isolation ON todo_tombstones;
+ALTER TABLE todo_tombstones NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE todo_tombstones DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS todo_dependencies_user_isolation ON todo_dependencies;
+ALTER TABLE todo_dependencies NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE todo_dependencies DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS todo_state_transitions_user_isolation ON todo_state_transitions;
+ALTER TABLE todo_state_transitions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE todo_state_transitions DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS workflow_states_todo_read ON workflow_states;
+DROP POLICY IF EXISTS workflow_states_user_isolation ON workflow_states;
+ALTER TABLE workflow_states NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE workflow_states DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS notifications_actor_insert ON notifications;
+DROP POLICY IF EXISTS notifications_actor_read ON notifications;
+DROP POLICY IF EXISTS notifications_user_isolation ON notifications;
+ALTER TABLE notifications NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE notifications DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS idempotency_keys_user_isolation ON idempotency_keys;
+ALTER TABLE idempotency_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS undo_tokens_user_isolation ON undo_tokens;
+ALTER TABLE undo_tokens NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE undo_tokens DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS saved_views_user_isolation ON saved_views;
+ALTER TABLE saved_views NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE saved_views DISABLE ROW LEVEL SECURITY;
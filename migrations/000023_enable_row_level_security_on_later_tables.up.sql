@@ -0,0 +1,88 @@
+-- migrations/000023_enable_row_level_security_on_later_tables.up.sql
+
+-- The tables added since 000007 get the same second line of defense as
+-- todos and projects. Rows that belong to a user are visible to that user;
+-- rows about a todo or a project are visible whenever it is (the subqueries
+-- are themselves filtered by its policy).
+
+-- Saved views, undo tokens and idempotency keys (with the responses they
+-- replay): only their user's
+ALTER TABLE saved_views ENABLE ROW LEVEL SECURITY;
+ALTER TABLE saved_views FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY saved_views_user_isolation ON saved_views
+    USING (app_rls_bypassed() OR user_id = app_user_id());
+
+ALTER TABLE undo_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE undo_tokens FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY undo_tokens_user_isolation ON undo_tokens
+    USING (app_rls_bypassed() OR user_id = app_user_id());
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY idempotency_keys_user_isolation ON idempotency_keys
+    USING (app_rls_bypassed() OR user_id = app_user_id());
+
+-- Notifications: read and marked read by their recipient, written by the user
+-- whose action they report, who can also read them back
+ALTER TABLE notifications ENABLE ROW LEVEL SECURITY;
+ALTER TABLE notifications FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY notifications_user_isolation ON notifications
+    USING (app_rls_bypassed() OR user_id = app_user_id());
+
+CREATE POLICY notifications_actor_read ON notifications
+    FOR SELECT
+    USING (actor_id = app_user_id());
+
+CREATE POLICY notifications_actor_insert ON notifications
+    FOR INSERT
+    WITH CHECK (app_rls_bypassed() OR actor_id = app_user_id());
+
+-- Workflow states: changed by those who can see the project, and also read by
+-- those who can see one of its todos, so a todo shared on its own still shows
+-- its state
+ALTER TABLE workflow_states ENABLE ROW LEVEL SECURITY;
+ALTER TABLE workflow_states FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY workflow_states_user_isolation ON workflow_states
+    USING (
+        app_rls_bypassed()
+        OR EXISTS (SELECT 1 FROM projects p WHERE p.id = workflow_states.project_id)
+    );
+
+CREATE POLICY workflow_states_todo_read ON workflow_states
+    FOR SELECT
+    USING (EXISTS (SELECT 1 FROM todos t WHERE t.project_id = workflow_states.project_id));
+
+-- State transitions and dependencies: visible whenever their todo is
+ALTER TABLE todo_state_transitions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_state_transitions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY todo_state_transitions_user_isolation ON todo_state_transitions
+    USING (
+        app_rls_bypassed()
+        OR EXISTS (SELECT 1 FROM todos t WHERE t.id = todo_state_transitions.todo_id)
+    );
+
+ALTER TABLE todo_dependencies ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_dependencies FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY todo_dependencies_user_isolation ON todo_dependencies
+    USING (
+        app_rls_bypassed()
+        OR EXISTS (SELECT 1 FROM todos t WHERE t.id = todo_dependencies.todo_id)
+    );
+
+-- Tombstones outlive their todo, so they are visible to the members of its
+-- workspace; the trigger that writes them runs as the user deleting the todo
+ALTER TABLE todo_tombstones ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_tombstones FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY todo_tombstones_user_isolation ON todo_tombstones
+    USING (
+        app_rls_bypassed()
+        OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = app_user_id())
+    );
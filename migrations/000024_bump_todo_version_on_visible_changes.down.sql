@@ -0,0 +1,9 @@
+-- migrations/000024_bump_todo_version_on_visible_changes.down.sql
+
+CREATE OR REPLACE FUNCTION bump_todo_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,36 @@
+-- migrations/000024_bump_todo_version_on_visible_changes.up.sql
+
+-- The version used to move on with every UPDATE, including ones that change
+-- nothing a client sees, such as the search language or a write that leaves
+-- every field as it was. It now only moves when a field of the todo's JSON
+-- does: the fields a revision records (see migrations/000017) and position,
+-- so a move in the manual order changes the ETag too.
+--
+-- Rebalancing is the exception: it rewrites the position keys of a whole
+-- workspace without changing their order, and bumping every todo there would
+-- make clients' If-Match fail for todos nobody touched. It runs in the system
+-- scope and sets app.rebalancing_positions; the setting alone, which any
+-- connection can set, is not enough.
+CREATE OR REPLACE FUNCTION bump_todo_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.title IS DISTINCT FROM OLD.title
+        OR NEW.description IS DISTINCT FROM OLD.description
+        OR NEW.completed IS DISTINCT FROM OLD.completed
+        OR NEW.project_id IS DISTINCT FROM OLD.project_id
+        OR NEW.assignee_id IS DISTINCT FROM OLD.assignee_id
+        OR NEW.state_id IS DISTINCT FROM OLD.state_id
+        OR NEW.archived_at IS DISTINCT FROM OLD.archived_at
+        OR NEW.deleted_at IS DISTINCT FROM OLD.deleted_at
+        OR (NEW.position IS DISTINCT FROM OLD.position AND NOT (
+            app_rls_bypassed()
+            AND COALESCE(current_setting('app.rebalancing_positions', true), '') = 'on'
+        ))
+    THEN
+        NEW.version = OLD.version + 1;
+    ELSE
+        NEW.version = OLD.version;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;